@@ -0,0 +1,92 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+func TestDepositTransactionToMessage(t *testing.T) {
+	var (
+		from    = common.HexToAddress("0x1")
+		to      = common.HexToAddress("0x2")
+		signer  = types.LatestSignerForChainID(big.NewInt(1))
+		baseFee = big.NewInt(1000)
+	)
+	for _, test := range []struct {
+		name string
+		to   *common.Address
+	}{
+		{name: "call", to: &to},
+		{name: "creation", to: nil},
+	} {
+		tx := types.NewTx(&types.DepositTx{
+			SourceHash:          common.HexToHash("0x1234"),
+			From:                from,
+			To:                  test.to,
+			Mint:                big.NewInt(5),
+			Value:               big.NewInt(3),
+			Gas:                 50000,
+			IsSystemTransaction: true,
+			Data:                []byte{0x01},
+		})
+		// The deposit carries no signature, so the sender must not be recovered.
+		msg, err := TransactionToMessage(tx, signer, baseFee)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if msg.From != from {
+			t.Errorf("%s: wrong from %x", test.name, msg.From)
+		}
+		if (msg.To == nil) != (test.to == nil) || (msg.To != nil && *msg.To != *test.to) {
+			t.Errorf("%s: wrong to %v", test.name, msg.To)
+		}
+		if msg.GasPrice.Sign() != 0 || msg.GasFeeCap.Sign() != 0 || msg.GasTipCap.Sign() != 0 {
+			t.Errorf("%s: non-zero fee fields: price %d, fee cap %d, tip cap %d", test.name, msg.GasPrice, msg.GasFeeCap, msg.GasTipCap)
+		}
+		if !msg.IsDepositTx || !msg.IsSystemTx {
+			t.Errorf("%s: wrong flags: deposit %v, system %v", test.name, msg.IsDepositTx, msg.IsSystemTx)
+		}
+		if msg.Mint.Cmp(big.NewInt(5)) != 0 || msg.Value.Cmp(big.NewInt(3)) != 0 {
+			t.Errorf("%s: wrong mint %d or value %d", test.name, msg.Mint, msg.Value)
+		}
+		if msg.GasLimit != 50000 || msg.Nonce != 0 || !bytes.Equal(msg.Data, []byte{0x01}) {
+			t.Errorf("%s: wrong gas limit %d, nonce %d or data %x", test.name, msg.GasLimit, msg.Nonce, msg.Data)
+		}
+	}
+}
+
+// Tracers build messages with the signer of the traced block, which may predate
+// London. The deposit sender must still be the embedded address.
+func TestDepositTransactionToMessageSigners(t *testing.T) {
+	from := common.HexToAddress("0x1")
+	tx := types.NewTx(&types.DepositTx{SourceHash: common.HexToHash("0x1234"), From: from, Value: big.NewInt(0), Gas: 50000})
+	for _, signer := range []types.Signer{types.HomesteadSigner{}, types.NewEIP155Signer(big.NewInt(1)), types.LatestSignerForChainID(big.NewInt(1))} {
+		msg, err := TransactionToMessage(tx, signer, nil)
+		if err != nil {
+			t.Fatalf("%T: %v", signer, err)
+		}
+		if msg.From != from {
+			t.Errorf("%T: wrong from %x", signer, msg.From)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// DepositEventTopic is the topic of the TransactionDeposited event emitted by
+// the L1 deposit contract:
+//
+//	event TransactionDeposited(address indexed from, address indexed to, uint256 indexed version, bytes opaqueData);
+var DepositEventTopic = crypto.Keccak256Hash([]byte("TransactionDeposited(address,address,uint256,bytes)"))
+
+// depositEventVersion0 is the only known version of the TransactionDeposited
+// event. Its opaque data is the tight packing of
+//
+//	uint256 mint, uint256 value, uint64 gasLimit, bool isCreation, bytes data
+var depositEventVersion0 = common.Hash{}
+
+// DepositTxFromLog derives the user deposit transaction for a TransactionDeposited
+// event log of the L1 deposit contract, the same way the rollup node does. The
+// source hash is derived from the block hash and index of the log, which must
+// therefore be set.
+func DepositTxFromLog(log *Log) (*Transaction, error) {
+	if len(log.Topics) != 4 {
+		return nil, fmt.Errorf("invalid deposit event: have %d topics, want 4", len(log.Topics))
+	}
+	if log.Topics[0] != DepositEventTopic {
+		return nil, fmt.Errorf("invalid deposit event topic %v, want %v", log.Topics[0], DepositEventTopic)
+	}
+	if version := log.Topics[3]; version != depositEventVersion0 {
+		return nil, fmt.Errorf("unsupported deposit event version %v", version)
+	}
+	opaqueData, err := unpackDepositEventData(log.Data)
+	if err != nil {
+		return nil, err
+	}
+	if len(opaqueData) < 32+32+8+1 {
+		return nil, fmt.Errorf("invalid deposit event: opaque data too short (%d bytes)", len(opaqueData))
+	}
+	dep := &DepositTx{
+		SourceHash: DepositSourceHash(log.BlockHash, uint64(log.Index), UserDepositSourceDomain),
+		From:       common.BytesToAddress(log.Topics[1][12:]),
+		Value:      new(big.Int).SetBytes(opaqueData[32:64]),
+		Gas:        binary.BigEndian.Uint64(opaqueData[64:72]),
+		Data:       common.CopyBytes(opaqueData[73:]),
+	}
+	// A zero mint is represented as nil.
+	if mint := new(big.Int).SetBytes(opaqueData[:32]); mint.Sign() != 0 {
+		dep.Mint = mint
+	}
+	if opaqueData[72] == 0 {
+		to := common.BytesToAddress(log.Topics[2][12:])
+		dep.To = &to
+	}
+	return NewTx(dep), nil
+}
+
+// unpackDepositEventData returns the opaque data of the ABI-encoded data of a
+// TransactionDeposited event, which must be minimally padded.
+func unpackDepositEventData(data []byte) ([]byte, error) {
+	if len(data) < 64 || len(data)%32 != 0 {
+		return nil, fmt.Errorf("invalid deposit event data length %d", len(data))
+	}
+	offset := new(big.Int).SetBytes(data[:32])
+	if !offset.IsUint64() || offset.Uint64() != 32 {
+		return nil, fmt.Errorf("invalid deposit event data offset %d", offset)
+	}
+	size := new(big.Int).SetBytes(data[32:64])
+	rest := uint64(len(data) - 64)
+	if !size.IsUint64() || size.Uint64() > rest || size.Uint64()+32 <= rest {
+		return nil, fmt.Errorf("invalid deposit event data size %d for %d bytes", size, rest)
+	}
+	return data[64 : 64+size.Uint64()], nil
+}
@@ -0,0 +1,105 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+// depositLogFixture returns a TransactionDeposited log for a deposit from
+// 0x...aa to 0x...bb, minting 100 wei and transferring 5 wei with a gas limit
+// of 100000 and calldata 0xdeadbeef.
+func depositLogFixture() *Log {
+	return &Log{
+		Address: common.HexToAddress("0xbEb5Fc579115071764c7423A4f12eDde41f106Ed"),
+		Topics: []common.Hash{
+			common.HexToHash("0xb3813568d9991fc951961fcb4c784893574240a28925604d09fc577c55bb7c32"),
+			common.HexToHash("0x00000000000000000000000000000000000000000000000000000000000000aa"),
+			common.HexToHash("0x00000000000000000000000000000000000000000000000000000000000000bb"),
+			{},
+		},
+		Data: common.FromHex("0x" +
+			"0000000000000000000000000000000000000000000000000000000000000020" +
+			"000000000000000000000000000000000000000000000000000000000000004d" +
+			"0000000000000000000000000000000000000000000000000000000000000064" +
+			"0000000000000000000000000000000000000000000000000000000000000005" +
+			"00000000000186a000deadbeef00000000000000000000000000000000000000"),
+		BlockHash: common.HexToHash("0xd25df7858efc1778118fb133ac561b138845361626dfb976699c5287ed0f4959"),
+		Index:     1,
+	}
+}
+
+func TestDepositTxFromLog(t *testing.T) {
+	require.Equal(t, common.HexToHash("0xb3813568d9991fc951961fcb4c784893574240a28925604d09fc577c55bb7c32"), DepositEventTopic)
+
+	tx, err := DepositTxFromLog(depositLogFixture())
+	require.NoError(t, err)
+
+	to := common.HexToAddress("0xbb")
+	want := NewTx(&DepositTx{
+		SourceHash: common.HexToHash("0xf923fb07134d7d287cb52c770cc619e17e82606c21a875c92f4c63b65280a5cc"),
+		From:       common.HexToAddress("0xaa"),
+		To:         &to,
+		Mint:       big.NewInt(100),
+		Value:      big.NewInt(5),
+		Gas:        100000,
+		Data:       common.FromHex("0xdeadbeef"),
+	})
+	require.Equal(t, want.Hash(), tx.Hash())
+	require.Equal(t, common.HexToHash("0x32be706e3d16b9bb2cd0695302506b1fac2aca79a4b58f2f1712e022900c4b41"), tx.Hash())
+	require.False(t, tx.IsSystemTx())
+	require.NoError(t, tx.Validate())
+
+	// Contract creations have no recipient, and a zero mint is nil.
+	log := depositLogFixture()
+	log.Data = common.CopyBytes(log.Data)
+	log.Data[64+31] = 0   // mint
+	log.Data[64+64+8] = 1 // isCreation
+	tx, err = DepositTxFromLog(log)
+	require.NoError(t, err)
+	require.Nil(t, tx.To())
+	require.Nil(t, tx.Mint())
+}
+
+func TestDepositTxFromLogInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		mod  func(*Log)
+		err  string
+	}{
+		{name: "Topic count", mod: func(l *Log) { l.Topics = l.Topics[:3] }, err: "have 3 topics"},
+		{name: "Event topic", mod: func(l *Log) { l.Topics[0] = common.Hash{0x01} }, err: "invalid deposit event topic"},
+		{name: "Version", mod: func(l *Log) { l.Topics[3] = common.Hash{31: 0x01} }, err: "unsupported deposit event version"},
+		{name: "Unaligned data", mod: func(l *Log) { l.Data = l.Data[:len(l.Data)-1] }, err: "invalid deposit event data length"},
+		{name: "Offset", mod: func(l *Log) { l.Data[31] = 0x40 }, err: "invalid deposit event data offset"},
+		{name: "Size too large", mod: func(l *Log) { l.Data[63] = 0x61 }, err: "invalid deposit event data size"},
+		{name: "Excess padding", mod: func(l *Log) { l.Data = append(l.Data, make([]byte, 32)...) }, err: "invalid deposit event data size"},
+		{name: "Short opaque data", mod: func(l *Log) { l.Data[63] = 0x48 }, err: "opaque data too short"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			log := depositLogFixture()
+			test.mod(log)
+			_, err := DepositTxFromLog(log)
+			require.ErrorContains(t, err, test.err)
+		})
+	}
+}
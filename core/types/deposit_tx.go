@@ -17,13 +17,30 @@
 package types
 
 import (
+	"encoding/binary"
+	"errors"
+	"fmt"
 	"math/big"
+	"sync"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
 )
 
 const DepositTxType = 0x7E
 
+// Source hash domains of deposit transactions, see DepositSourceHash.
+const (
+	UserDepositSourceDomain   = 0
+	L1InfoDepositSourceDomain = 1
+)
+
+var (
+	ErrDepositZeroSourceHash = errors.New("deposit transaction has zero source hash")
+	ErrDepositZeroFrom       = errors.New("deposit transaction has zero from address")
+	ErrDepositZeroGas        = errors.New("deposit transaction gas must be non-zero")
+)
+
 type DepositTx struct {
 	// SourceHash uniquely identifies the source of the deposit
 	SourceHash common.Hash
@@ -64,6 +81,143 @@ func (tx *DepositTx) copy() TxData {
 	return cpy
 }
 
+// DepositSourceHash computes the source hash of a deposit transaction derived
+// from L1 block l1BlockHash:
+//
+//	keccak256(bytes32(domain), keccak256(l1BlockHash, bytes32(index)))
+//
+// For user deposits (UserDepositSourceDomain), index is the index of the
+// deposit event log in the L1 block. For L1 attributes deposits
+// (L1InfoDepositSourceDomain), it is the L2 sequence number within the epoch.
+func DepositSourceHash(l1BlockHash common.Hash, logIndex uint64, domain uint64) common.Hash {
+	var input [32 * 2]byte
+	copy(input[:32], l1BlockHash[:])
+	binary.BigEndian.PutUint64(input[32*2-8:], logIndex)
+	depositIDHash := crypto.Keccak256Hash(input[:])
+
+	var domainInput [32 * 2]byte
+	binary.BigEndian.PutUint64(domainInput[32-8:32], domain)
+	copy(domainInput[32:], depositIDHash[:])
+	return crypto.Keccak256Hash(domainInput[:])
+}
+
+// SourceHashKey returns the source hash of a deposit transaction, for use as a
+// deduplication key. The boolean is false for all other transactions, which
+// have no source hash.
+func SourceHashKey(tx *Transaction) (common.Hash, bool) {
+	dep, ok := tx.depositTx()
+	if !ok {
+		return common.Hash{}, false
+	}
+	return dep.SourceHash, true
+}
+
+// SourceHashSet is a set of deposit source hashes, used to detect replayed
+// deposits. It is safe for concurrent use. The zero value is an empty set.
+type SourceHashSet struct {
+	mu     sync.RWMutex
+	hashes map[common.Hash]struct{}
+}
+
+// Add inserts the source hash of a deposit transaction into the set. It returns
+// false if the hash was already present or the transaction is not a deposit.
+func (s *SourceHashSet) Add(tx *Transaction) bool {
+	h, ok := SourceHashKey(tx)
+	if !ok {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.hashes[h]; ok {
+		return false
+	}
+	if s.hashes == nil {
+		s.hashes = make(map[common.Hash]struct{})
+	}
+	s.hashes[h] = struct{}{}
+	return true
+}
+
+// Contains reports whether the source hash of a deposit transaction is in the
+// set. It returns false for all other transactions.
+func (s *SourceHashSet) Contains(tx *Transaction) bool {
+	h, ok := SourceHashKey(tx)
+	if !ok {
+		return false
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	_, ok = s.hashes[h]
+	return ok
+}
+
+// Len returns the number of source hashes in the set.
+func (s *SourceHashSet) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return len(s.hashes)
+}
+
+// validate checks the invariants every derived deposit transaction satisfies.
+// It is applied when decoding deposits from their binary encoding, so that
+// corrupted envelopes are rejected instead of decoding into a bogus deposit.
+func (tx *DepositTx) validate() error {
+	if tx.SourceHash == (common.Hash{}) {
+		return ErrDepositZeroSourceHash
+	}
+	if tx.From == (common.Address{}) {
+		return ErrDepositZeroFrom
+	}
+	return nil
+}
+
+// DepositWarning reports a deposit transaction which is valid, but most likely
+// not what its producer intended.
+type DepositWarning struct {
+	Reason string
+}
+
+func (w *DepositWarning) Error() string {
+	return "suspicious deposit transaction: " + w.Reason
+}
+
+// CheckDeposit runs opt-in plausibility checks on a deposit transaction, beyond
+// the validity rules enforced by Validate. It returns a *DepositWarning if the
+// deposit looks like a producer bug, and nil for valid-looking deposits and all
+// other transaction types. Currently it flags deposits which mint ETH but
+// transfer no value, where the producer likely meant to credit the recipient.
+func (tx *Transaction) CheckDeposit() error {
+	dep, ok := tx.depositTx()
+	if !ok {
+		return nil
+	}
+	if dep.Mint != nil && dep.Mint.Sign() > 0 && (dep.Value == nil || dep.Value.Sign() == 0) {
+		return &DepositWarning{Reason: fmt.Sprintf("mint of %d with zero value", dep.Mint)}
+	}
+	return nil
+}
+
+// RebuildDepositTx returns a copy of the deposit transaction tx with mut applied
+// to a deep copy of its inner DepositTx. The effective nonce, if any, is kept.
+// The returned transaction does not share any cached values with tx. It is
+// meant for tests which need a variant of an existing deposit.
+func RebuildDepositTx(tx *Transaction, mut func(*DepositTx)) (*Transaction, error) {
+	dep, ok := tx.depositTx()
+	if !ok {
+		return nil, fmt.Errorf("%w: transaction type %d is not a deposit", ErrTxTypeNotSupported, tx.Type())
+	}
+	cpy := dep.copy().(*DepositTx)
+	mut(cpy)
+	var inner TxData = cpy
+	if wn, ok := tx.inner.(*depositTxWithNonce); ok {
+		inner = &depositTxWithNonce{DepositTx: *cpy, EffectiveNonce: wn.EffectiveNonce}
+	}
+	return &Transaction{inner: inner, time: tx.time}, nil
+}
+
 // accessors for innerTx.
 func (tx *DepositTx) txType() byte              { return DepositTxType }
 func (tx *DepositTx) chainID() *big.Int         { return common.Big0 }
@@ -0,0 +1,508 @@
+package types
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDepositTxDecodeValidation(t *testing.T) {
+	tests := []struct {
+		name        string
+		modifier    func(tx *DepositTx)
+		expectedErr error
+	}{
+		{
+			name:     "Valid",
+			modifier: func(tx *DepositTx) {},
+		},
+		{
+			name:        "Zero source hash",
+			modifier:    func(tx *DepositTx) { tx.SourceHash = common.Hash{} },
+			expectedErr: ErrDepositZeroSourceHash,
+		},
+		{
+			name:        "Zero from",
+			modifier:    func(tx *DepositTx) { tx.From = common.Address{} },
+			expectedErr: ErrDepositZeroFrom,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			inner := &DepositTx{
+				SourceHash: common.HexToHash("0x1234"),
+				From:       common.HexToAddress("0x1"),
+				Value:      big.NewInt(1),
+				Gas:        1000,
+			}
+			test.modifier(inner)
+			enc, err := NewTx(inner).MarshalBinary()
+			require.NoError(t, err)
+
+			err = new(Transaction).UnmarshalBinary(enc)
+			require.ErrorIs(t, err, test.expectedErr)
+		})
+	}
+}
+
+func TestTransactionIsSystemTx(t *testing.T) {
+	require.True(t, NewTx(&DepositTx{IsSystemTransaction: true}).IsSystemTx())
+	require.False(t, NewTx(&DepositTx{IsSystemTransaction: false}).IsSystemTx())
+	require.False(t, NewTx(&LegacyTx{}).IsSystemTx())
+
+	// Deposits decoded with a nonce must report the flag too.
+	nonce := uint64(7)
+	enc, err := json.Marshal(NewTx(&DepositTx{
+		SourceHash:          common.HexToHash("0x1234"),
+		From:                common.HexToAddress("0x1"),
+		Gas:                 1000,
+		IsSystemTransaction: true,
+	}))
+	require.NoError(t, err)
+	enc = bytes.Replace(enc, []byte(`"nonce":null`), []byte(fmt.Sprintf(`"nonce":"%#x"`, nonce)), 1)
+	var tx Transaction
+	require.NoError(t, json.Unmarshal(enc, &tx))
+	require.Equal(t, nonce, *tx.EffectiveNonce())
+	require.True(t, tx.IsSystemTx())
+}
+
+func TestDepositSourceHash(t *testing.T) {
+	l1BlockHash := common.HexToHash("0xd25df7858efc1778118fb133ac561b138845361626dfb976699c5287ed0f4959")
+
+	// Vector from the Hashing.hashDepositSource test of the OP-stack contracts.
+	require.Equal(t,
+		common.HexToHash("0xf923fb07134d7d287cb52c770cc619e17e82606c21a875c92f4c63b65280a5cc"),
+		DepositSourceHash(l1BlockHash, 1, UserDepositSourceDomain))
+
+	// L1 attributes deposits use the same scheme in a separate domain:
+	// keccak256(abi.encode(bytes32(1), keccak256(abi.encode(l1BlockHash, seqNumber)))).
+	depositID := crypto.Keccak256(l1BlockHash[:], common.LeftPadBytes([]byte{5}, 32))
+	want := crypto.Keccak256Hash(common.LeftPadBytes([]byte{L1InfoDepositSourceDomain}, 32), depositID)
+	require.Equal(t, want, DepositSourceHash(l1BlockHash, 5, L1InfoDepositSourceDomain))
+	require.NotEqual(t, want, DepositSourceHash(l1BlockHash, 5, UserDepositSourceDomain))
+}
+
+func TestTransactionWithSourceHash(t *testing.T) {
+	var (
+		oldHash = common.HexToHash("0x1234")
+		newHash = common.HexToHash("0x5678")
+		orig    = NewTx(&DepositTx{SourceHash: oldHash, From: common.HexToAddress("0x1"), Gas: 1000, Value: big.NewInt(1), Data: []byte{0x01}})
+	)
+	origTxHash := orig.Hash()
+
+	cpy, err := orig.WithSourceHash(newHash)
+	require.NoError(t, err)
+	require.Equal(t, uint8(DepositTxType), cpy.Type())
+	require.Equal(t, newHash, cpy.SourceHash())
+	require.NotEqual(t, origTxHash, cpy.Hash())
+
+	// The original transaction and its cached hash are unchanged.
+	require.Equal(t, oldHash, orig.SourceHash())
+	require.Equal(t, origTxHash, orig.Hash())
+
+	// Replacing the source hash with the original one restores the hash.
+	back, err := cpy.WithSourceHash(oldHash)
+	require.NoError(t, err)
+	require.Equal(t, origTxHash, back.Hash())
+
+	_, err = NewTx(&LegacyTx{Gas: 21000, GasPrice: big.NewInt(1)}).WithSourceHash(newHash)
+	require.ErrorIs(t, err, ErrTxTypeNotSupported)
+}
+
+func TestTransactionWithSourceHashEffectiveNonce(t *testing.T) {
+	var orig Transaction
+	require.NoError(t, orig.UnmarshalJSON([]byte(`{"type":"0x7e","nonce":"0x5","to":null,"gas":"0x1234","value":"0x1","input":"0x","sourceHash":"0x0000000000000000000000000000000000000000000000000000000000001234","from":"0x0000000000000000000000000000000000000001"}`)))
+
+	cpy, err := orig.WithSourceHash(common.HexToHash("0x5678"))
+	require.NoError(t, err)
+	require.Equal(t, orig.EffectiveNonce(), cpy.EffectiveNonce())
+	require.NotEqual(t, orig.Hash(), cpy.Hash())
+}
+
+func TestTransactionDepositMint(t *testing.T) {
+	from := common.HexToAddress("0x1")
+	tests := []struct {
+		name      string
+		tx        *Transaction
+		mint      *big.Int
+		isDeposit bool
+	}{
+		{
+			name:      "DepositWithMint",
+			tx:        NewTx(&DepositTx{From: from, Mint: big.NewInt(100), Value: big.NewInt(1), Gas: 1000}),
+			mint:      big.NewInt(100),
+			isDeposit: true,
+		},
+		{
+			name:      "DepositWithoutMint",
+			tx:        NewTx(&DepositTx{From: from, Value: big.NewInt(1), Gas: 1000}),
+			isDeposit: true,
+		},
+		{
+			name: "Legacy",
+			tx:   NewTx(&LegacyTx{GasPrice: big.NewInt(1), Gas: 21000}),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mint, ok := test.tx.DepositMint()
+			require.Equal(t, test.isDeposit, ok)
+			require.Equal(t, test.mint, mint)
+			require.Equal(t, test.mint, test.tx.Mint())
+		})
+	}
+}
+
+func TestTransactionDepositMintEffectiveNonce(t *testing.T) {
+	var tx Transaction
+	require.NoError(t, tx.UnmarshalJSON([]byte(`{"type":"0x7e","nonce":"0x5","to":null,"gas":"0x1234","mint":"0x64","value":"0x1","input":"0x","sourceHash":"0x0000000000000000000000000000000000000000000000000000000000001234","from":"0x0000000000000000000000000000000000000001"}`)))
+
+	mint, ok := tx.DepositMint()
+	require.True(t, ok)
+	require.Equal(t, big.NewInt(100), mint)
+	require.Equal(t, big.NewInt(100), tx.Mint())
+}
+
+func TestDepositTxUnmarshalJSONZeroGas(t *testing.T) {
+	const depositJSON = `{"type":"0x7e","to":null,"gas":"%s","value":"0x1","input":"0x","sourceHash":"0x0000000000000000000000000000000000000000000000000000000000001234","from":"0x0000000000000000000000000000000000000001"}`
+
+	var tx Transaction
+	require.NoError(t, tx.UnmarshalJSONUntrusted([]byte(fmt.Sprintf(depositJSON, "0x1"))))
+	require.Equal(t, uint64(1), tx.Gas())
+
+	// Zero-gas deposits are valid, e.g. in blocks read from a node, and only
+	// rejected from untrusted sources.
+	require.NoError(t, tx.UnmarshalJSON([]byte(fmt.Sprintf(depositJSON, "0x0"))))
+	require.Zero(t, tx.Gas())
+	require.ErrorIs(t, tx.UnmarshalJSONUntrusted([]byte(fmt.Sprintf(depositJSON, "0x0"))), ErrDepositZeroGas)
+
+	AllowZeroGasDeposits = true
+	defer func() { AllowZeroGasDeposits = false }()
+	require.NoError(t, tx.UnmarshalJSONUntrusted([]byte(fmt.Sprintf(depositJSON, "0x0"))))
+	require.Zero(t, tx.Gas())
+}
+
+func TestDepositTxUnmarshalJSONUntrusted(t *testing.T) {
+	const depositJSON = `{"type":"0x7e","to":null,"gas":"0x1234","value":"0x1","input":"0x","sourceHash":"0x0000000000000000000000000000000000000000000000000000000000001234","from":"0x0000000000000000000000000000000000000001","isSystemTx":true}`
+
+	var trusted, untrusted Transaction
+	require.NoError(t, trusted.UnmarshalJSON([]byte(depositJSON)))
+	require.True(t, trusted.IsSystemTx())
+
+	require.NoError(t, untrusted.UnmarshalJSONUntrusted([]byte(depositJSON)))
+	require.False(t, untrusted.IsSystemTx())
+	require.Equal(t, uint8(DepositTxType), untrusted.Type())
+	require.Equal(t, trusted.SourceHash(), untrusted.SourceHash())
+	require.NotEqual(t, trusted.Hash(), untrusted.Hash())
+
+	// Deposits from the zero address are only accepted from trusted sources.
+	zeroFrom := strings.Replace(depositJSON, "0x0000000000000000000000000000000000000001", "0x0000000000000000000000000000000000000000", 1)
+	require.NoError(t, new(Transaction).UnmarshalJSON([]byte(zeroFrom)))
+	require.ErrorIs(t, new(Transaction).UnmarshalJSONUntrusted([]byte(zeroFrom)), ErrDepositZeroFrom)
+
+	// Other transaction types decode as usual.
+	legacy := `{"type":"0x0","nonce":"0x0","to":null,"gas":"0x5208","gasPrice":"0x1","value":"0x0","input":"0x","v":"0x0","r":"0x0","s":"0x0"}`
+	var want, have Transaction
+	require.NoError(t, want.UnmarshalJSON([]byte(legacy)))
+	require.NoError(t, have.UnmarshalJSONUntrusted([]byte(legacy)))
+	require.Equal(t, want.Hash(), have.Hash())
+}
+
+func TestDepositTxEffectiveNonceJSONRoundTrip(t *testing.T) {
+	const depositJSON = `{"type":"0x7e","nonce":"0x5","to":null,"gas":"0x1234","mint":"0x64","value":"0x1","input":"0x01","sourceHash":"0x0000000000000000000000000000000000000000000000000000000000001234","from":"0x0000000000000000000000000000000000000001"}`
+	var tx Transaction
+	require.NoError(t, tx.UnmarshalJSON([]byte(depositJSON)))
+
+	enc, err := tx.MarshalJSON()
+	require.NoError(t, err)
+	const want = `{"type":"0x7e","nonce":"0x5","gasPrice":null,"maxPriorityFeePerGas":null,"maxFeePerGas":null,"gas":"0x1234","value":"0x1","input":"0x01","v":null,"r":null,"s":null,"to":null,"sourceHash":"0x0000000000000000000000000000000000000000000000000000000000001234","from":"0x0000000000000000000000000000000000000001","isSystemTx":false,"mint":"0x64","hash":"%s"}`
+	require.JSONEq(t, fmt.Sprintf(want, tx.Hash().Hex()), string(enc))
+
+	var dec Transaction
+	require.NoError(t, dec.UnmarshalJSON(enc))
+	require.Equal(t, tx.Hash(), dec.Hash())
+	require.Equal(t, uint64(5), *dec.EffectiveNonce())
+	require.Equal(t, []byte{0x01}, dec.Data())
+
+	// Without an effective nonce, the nonce is null.
+	enc, err = NewTx(&tx.inner.(*depositTxWithNonce).DepositTx).MarshalJSON()
+	require.NoError(t, err)
+	require.Contains(t, string(enc), `"nonce":null`)
+}
+
+func TestTransactionCheckDeposit(t *testing.T) {
+	deposit := func(mint, value *big.Int) *Transaction {
+		return NewTx(&DepositTx{SourceHash: common.HexToHash("0x1234"), From: common.HexToAddress("0x1"), Gas: 1000, Mint: mint, Value: value})
+	}
+	require.NoError(t, deposit(big.NewInt(10), big.NewInt(10)).CheckDeposit())
+	require.NoError(t, deposit(big.NewInt(10), big.NewInt(3)).CheckDeposit())
+	require.NoError(t, deposit(nil, big.NewInt(0)).CheckDeposit())
+	require.NoError(t, deposit(big.NewInt(0), big.NewInt(0)).CheckDeposit())
+	require.NoError(t, NewTx(&LegacyTx{GasPrice: big.NewInt(1), Value: big.NewInt(0)}).CheckDeposit())
+
+	err := deposit(big.NewInt(10), big.NewInt(0)).CheckDeposit()
+	var warning *DepositWarning
+	require.ErrorAs(t, err, &warning)
+	require.EqualError(t, err, "suspicious deposit transaction: mint of 10 with zero value")
+
+	// The check is opt-in: such deposits remain valid.
+	require.NoError(t, deposit(big.NewInt(10), big.NewInt(0)).Validate())
+}
+
+// TestDepositTxEncodingVectors pins the binary encoding and hash of deposit
+// transactions. Derivation depends on these being stable.
+func TestDepositTxEncodingVectors(t *testing.T) {
+	l1Block := common.HexToAddress("0x4200000000000000000000000000000000000015")
+	tests := []struct {
+		name string
+		tx   *DepositTx
+		enc  string
+		hash common.Hash
+	}{
+		{
+			name: "System call",
+			tx: &DepositTx{
+				SourceHash:          common.HexToHash("0x01"),
+				From:                common.HexToAddress("0xdeaddeaddeaddeaddeaddeaddeaddeaddead0001"),
+				To:                  &l1Block,
+				Value:               big.NewInt(0),
+				Gas:                 1000000,
+				IsSystemTransaction: true,
+				Data:                common.FromHex("0x015d8eb9"),
+			},
+			enc:  "0x7ef857a0000000000000000000000000000000000000000000000000000000000000000194deaddeaddeaddeaddeaddeaddeaddeaddead00019442000000000000000000000000000000000000158080830f42400184015d8eb9",
+			hash: common.HexToHash("0xa7d84b541269850741af614d896aa6f53712af662d1e41473f9f8b9ae36e19cd"),
+		},
+		{
+			name: "Creation with mint",
+			tx: &DepositTx{
+				SourceHash: common.HexToHash("0x02"),
+				From:       common.HexToAddress("0x01"),
+				Mint:       big.NewInt(1e18),
+				Value:      big.NewInt(1e18),
+				Gas:        100000,
+				Data:       common.FromHex("0x6001"),
+			},
+			enc:  "0x7ef851a0000000000000000000000000000000000000000000000000000000000000000294000000000000000000000000000000000000000180880de0b6b3a7640000880de0b6b3a7640000830186a080826001",
+			hash: common.HexToHash("0x846e57dc53ff8f726548847882c8a4a71244eccb639ec7e7d045ebb1d39c8248"),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tx := NewTx(test.tx)
+			enc, err := tx.MarshalBinary()
+			require.NoError(t, err)
+			require.Equal(t, test.enc, hexutil.Encode(enc))
+			require.Equal(t, test.hash, tx.Hash())
+
+			var dec Transaction
+			require.NoError(t, dec.UnmarshalBinary(enc))
+			require.Equal(t, test.hash, dec.Hash())
+		})
+	}
+}
+
+func TestTransactionHashExcludingNonce(t *testing.T) {
+	const depositJSON = `{"type":"0x7e",%s"to":null,"gas":"0x1234","value":"0x1","input":"0x","sourceHash":"0x0000000000000000000000000000000000000000000000000000000000001234","from":"0x0000000000000000000000000000000000000001"}`
+	var withNonce, withoutNonce Transaction
+	require.NoError(t, withNonce.UnmarshalJSON([]byte(fmt.Sprintf(depositJSON, `"nonce":"0x5",`))))
+	require.NoError(t, withoutNonce.UnmarshalJSON([]byte(fmt.Sprintf(depositJSON, ""))))
+	require.NotNil(t, withNonce.EffectiveNonce())
+
+	require.Equal(t, withoutNonce.HashExcludingNonce(), withNonce.HashExcludingNonce())
+	require.Equal(t, withoutNonce.Hash(), withNonce.HashExcludingNonce())
+	require.Equal(t, withNonce.Hash(), withNonce.HashExcludingNonce())
+
+	legacy := NewTx(&LegacyTx{Nonce: 5, GasPrice: big.NewInt(1), Value: big.NewInt(1)})
+	require.Equal(t, legacy.Hash(), legacy.HashExcludingNonce())
+}
+
+func TestDepositTxConformance(t *testing.T) {
+	to := common.HexToAddress("0x2")
+	deposit := DepositTx{
+		SourceHash:          common.HexToHash("0x1234"),
+		From:                common.HexToAddress("0x1"),
+		To:                  &to,
+		Mint:                big.NewInt(5),
+		Value:               big.NewInt(3),
+		Gas:                 50000,
+		IsSystemTransaction: true,
+		Data:                []byte{0x01},
+	}
+	assertTxDataConformance(t, new(DepositTx))
+	assertTxDataConformance(t, &deposit)
+	assertTxDataConformance(t, &depositTxWithNonce{DepositTx: deposit, EffectiveNonce: 7})
+}
+
+func TestSourceHashSet(t *testing.T) {
+	deposit := func(source common.Hash) *Transaction {
+		return NewTx(&DepositTx{SourceHash: source, From: common.HexToAddress("0x1"), Gas: 1000, Value: big.NewInt(0)})
+	}
+	var (
+		a      = deposit(DepositSourceHash(common.HexToHash("0xaa"), 0, UserDepositSourceDomain))
+		b      = deposit(DepositSourceHash(common.HexToHash("0xaa"), 0, L1InfoDepositSourceDomain))
+		legacy = NewTx(&LegacyTx{GasPrice: big.NewInt(1), Value: big.NewInt(0)})
+	)
+	key, ok := SourceHashKey(a)
+	require.True(t, ok)
+	require.Equal(t, a.SourceHash(), key)
+	_, ok = SourceHashKey(legacy)
+	require.False(t, ok)
+
+	var set SourceHashSet
+	require.False(t, set.Contains(a))
+	require.True(t, set.Add(a))
+	require.False(t, set.Add(a), "replayed deposit added")
+	require.True(t, set.Contains(a))
+	require.False(t, set.Contains(b))
+	require.True(t, set.Add(b))
+	require.False(t, set.Add(legacy))
+	require.False(t, set.Contains(legacy))
+	require.Equal(t, 2, set.Len())
+
+	// The effective nonce does not change the key.
+	var withNonce Transaction
+	require.NoError(t, withNonce.UnmarshalJSON([]byte(fmt.Sprintf(`{"type":"0x7e","nonce":"0x5","to":null,"gas":"0x3e8","value":"0x0","input":"0x","sourceHash":"%s","from":"0x0000000000000000000000000000000000000001"}`, a.SourceHash().Hex()))))
+	require.True(t, set.Contains(&withNonce))
+}
+
+func TestSourceHashSetConcurrent(t *testing.T) {
+	var (
+		set   SourceHashSet
+		added = make(chan bool, 8*100)
+		wg    sync.WaitGroup
+	)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				tx := NewTx(&DepositTx{SourceHash: common.BigToHash(big.NewInt(int64(j + 1))), From: common.HexToAddress("0x1"), Value: big.NewInt(0)})
+				added <- set.Add(tx)
+				set.Contains(tx)
+			}
+		}()
+	}
+	wg.Wait()
+	close(added)
+
+	var n int
+	for ok := range added {
+		if ok {
+			n++
+		}
+	}
+	require.Equal(t, 100, n)
+	require.Equal(t, 100, set.Len())
+}
+
+func TestRebuildDepositTx(t *testing.T) {
+	orig := NewTx(&DepositTx{SourceHash: common.HexToHash("0x1234"), From: common.HexToAddress("0x1"), Gas: 1000, Value: big.NewInt(1), Data: []byte{0x01}})
+	origHash := orig.Hash()
+
+	gas, err := RebuildDepositTx(orig, func(tx *DepositTx) { tx.Gas = 2000 })
+	require.NoError(t, err)
+	require.Equal(t, uint64(2000), gas.Gas())
+	require.NotEqual(t, origHash, gas.Hash())
+
+	data, err := RebuildDepositTx(orig, func(tx *DepositTx) { tx.Data[0] = 0x02 })
+	require.NoError(t, err)
+	require.Equal(t, []byte{0x02}, data.Data())
+	require.NotEqual(t, origHash, data.Hash())
+	require.NotEqual(t, gas.Hash(), data.Hash())
+
+	// The original transaction is unchanged.
+	require.Equal(t, uint64(1000), orig.Gas())
+	require.Equal(t, []byte{0x01}, orig.Data())
+	require.Equal(t, origHash, orig.Hash())
+
+	_, err = RebuildDepositTx(NewTx(&LegacyTx{GasPrice: big.NewInt(1)}), func(*DepositTx) {})
+	require.ErrorIs(t, err, ErrTxTypeNotSupported)
+}
+
+func TestTransactionValueDelta(t *testing.T) {
+	from := common.HexToAddress("0x1")
+	tests := []struct {
+		name string
+		tx   *Transaction
+		want *big.Int
+	}{
+		{
+			name: "DepositWithMint",
+			tx:   NewTx(&DepositTx{From: from, Mint: big.NewInt(100), Value: big.NewInt(1), Gas: 1000}),
+			want: big.NewInt(101),
+		},
+		{
+			name: "DepositWithoutMint",
+			tx:   NewTx(&DepositTx{From: from, Value: big.NewInt(1), Gas: 1000}),
+			want: big.NewInt(1),
+		},
+		{
+			name: "Legacy",
+			tx:   NewTx(&LegacyTx{GasPrice: big.NewInt(1), Gas: 21000, Value: big.NewInt(5)}),
+			want: big.NewInt(5),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			delta := test.tx.ValueDelta()
+			require.Equal(t, test.want, delta)
+
+			// The result is not shared with the transaction.
+			delta.SetInt64(0)
+			require.Equal(t, test.want, test.tx.ValueDelta())
+		})
+	}
+}
+
+func TestTransactionHasEffectiveNonce(t *testing.T) {
+	const depositJSON = `{"type":"0x7e",%s"to":null,"gas":"0x1234","value":"0x1","input":"0x","sourceHash":"0x0000000000000000000000000000000000000000000000000000000000001234","from":"0x0000000000000000000000000000000000000001"}`
+
+	var withNonce, withoutNonce Transaction
+	require.NoError(t, withNonce.UnmarshalJSON([]byte(fmt.Sprintf(depositJSON, `"nonce":"0x5",`))))
+	require.True(t, withNonce.HasEffectiveNonce())
+	require.Equal(t, uint64(5), *withNonce.EffectiveNonce())
+
+	require.NoError(t, withoutNonce.UnmarshalJSON([]byte(fmt.Sprintf(depositJSON, ""))))
+	require.False(t, withoutNonce.HasEffectiveNonce())
+
+	legacy := NewTx(&LegacyTx{Nonce: 5, GasPrice: big.NewInt(1)})
+	require.False(t, legacy.HasEffectiveNonce())
+	require.Equal(t, uint64(5), *legacy.EffectiveNonce())
+}
+
+func TestTransactionUnmarshalBinaryStrict(t *testing.T) {
+	dep := &DepositTx{SourceHash: common.HexToHash("0x1234"), From: common.HexToAddress("0x1"), Gas: 1000, Value: big.NewInt(1), Data: []byte{0x01}}
+	canonical, err := NewTx(dep).MarshalBinary()
+	require.NoError(t, err)
+
+	var tx Transaction
+	require.NoError(t, tx.UnmarshalBinaryStrict(canonical))
+	require.Equal(t, NewTx(dep).Hash(), tx.Hash())
+
+	// The same deposit with a zero-padded gas limit.
+	padded, err := rlp.EncodeToBytes([]interface{}{dep.SourceHash, dep.From, dep.To, dep.Mint, dep.Value, []byte{0x00, 0x03, 0xe8}, dep.IsSystemTransaction, dep.Data})
+	require.NoError(t, err)
+	padded = append([]byte{DepositTxType}, padded...)
+
+	// The RLP decoder rejects it on its own, and its error is passed through.
+	require.Error(t, new(Transaction).UnmarshalBinary(padded))
+	err = new(Transaction).UnmarshalBinaryStrict(padded)
+	require.ErrorContains(t, err, "non-canonical integer")
+	require.NotErrorIs(t, err, ErrTxNonCanonical)
+
+	// Other decoding errors are passed through.
+	err = new(Transaction).UnmarshalBinaryStrict([]byte{DepositTxType})
+	require.Error(t, err)
+	require.NotErrorIs(t, err, ErrTxNonCanonical)
+}
@@ -13,7 +13,7 @@ import (
 func (a AccessTuple) MarshalJSON() ([]byte, error) {
 	type AccessTuple struct {
 		Address     common.Address `json:"address"     gencodec:"required"`
-		StorageKeys []common.Hash  `json:"storageKeys" gencodec:"required"`
+		StorageKeys []common.Hash  `json:"storageKeys"`
 	}
 	var enc AccessTuple
 	enc.Address = a.Address
@@ -25,7 +25,7 @@ func (a AccessTuple) MarshalJSON() ([]byte, error) {
 func (a *AccessTuple) UnmarshalJSON(input []byte) error {
 	type AccessTuple struct {
 		Address     *common.Address `json:"address"     gencodec:"required"`
-		StorageKeys []common.Hash   `json:"storageKeys" gencodec:"required"`
+		StorageKeys []common.Hash   `json:"storageKeys"`
 	}
 	var dec AccessTuple
 	if err := json.Unmarshal(input, &dec); err != nil {
@@ -35,9 +35,8 @@ func (a *AccessTuple) UnmarshalJSON(input []byte) error {
 		return errors.New("missing required field 'address' for AccessTuple")
 	}
 	a.Address = *dec.Address
-	if dec.StorageKeys == nil {
-		return errors.New("missing required field 'storageKeys' for AccessTuple")
+	if dec.StorageKeys != nil {
+		a.StorageKeys = dec.StorageKeys
 	}
-	a.StorageKeys = dec.StorageKeys
 	return nil
 }
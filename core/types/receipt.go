@@ -209,6 +209,37 @@ func NewReceipt(root []byte, failed bool, cumulativeGasUsed uint64) *Receipt {
 	return r
 }
 
+// ReceiptForDeposit creates the receipt of a deposit transaction executed on its
+// own, so the cumulative gas used equals the gas used. The bloom, transaction
+// hash, deposit nonce and contract address are derived from the transaction and
+// logs, while the block inclusion fields are left for DeriveFields. It returns
+// nil if tx is not a deposit transaction.
+//
+// The contract address of a contract creation depends on the sender's nonce at
+// execution, which is only known for deposits carrying an effective nonce. For
+// other deposits it is left empty.
+func ReceiptForDeposit(tx *Transaction, status uint64, gasUsed uint64, logs []*Log) *Receipt {
+	dep, ok := tx.depositTx()
+	if !ok {
+		return nil
+	}
+	r := &Receipt{
+		Type:              DepositTxType,
+		Status:            status,
+		CumulativeGasUsed: gasUsed,
+		Logs:              logs,
+		TxHash:            tx.Hash(),
+		GasUsed:           gasUsed,
+		EffectiveGasPrice: new(big.Int),
+		DepositNonce:      tx.EffectiveNonce(),
+	}
+	r.Bloom = CreateBloom(Receipts{r})
+	if dep.To == nil && r.DepositNonce != nil {
+		r.ContractAddress = crypto.CreateAddress(dep.From, *r.DepositNonce)
+	}
+	return r
+}
+
 // EncodeRLP implements rlp.Encoder, and flattens the consensus fields of a receipt
 // into an RLP stream. If no post state is present, byzantium fork is assumed.
 func (r *Receipt) EncodeRLP(w io.Writer) error {
@@ -26,6 +26,7 @@ import (
 	"testing"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/holiman/uint256"
@@ -737,6 +738,53 @@ func TestBedrockDepositReceiptUnchanged(t *testing.T) {
 	require.Nil(t, parsed.DepositNonce)
 }
 
+func TestReceiptForDeposit(t *testing.T) {
+	from := common.HexToAddress("0x1234")
+	logs := []*Log{
+		{Address: common.HexToAddress("0x33"), Topics: []common.Hash{{0x01}}, Data: []byte{1}},
+		{Address: common.HexToAddress("0x0333"), Topics: []common.Hash{{0x02}, {0x03}}},
+	}
+	dep := DepositTx{SourceHash: common.Hash{0xaa}, From: from, Value: big.NewInt(1), Gas: 100_000}
+	tx := new(Transaction)
+	tx.setDecoded(&depositTxWithNonce{DepositTx: dep, EffectiveNonce: 7}, 0)
+
+	r := ReceiptForDeposit(tx, ReceiptStatusSuccessful, 50_000, logs)
+	require.Equal(t, uint8(DepositTxType), r.Type)
+	require.Equal(t, ReceiptStatusSuccessful, r.Status)
+	require.Equal(t, uint64(50_000), r.GasUsed)
+	require.Equal(t, uint64(50_000), r.CumulativeGasUsed)
+	require.Equal(t, CreateBloom(Receipts{&Receipt{Logs: logs}}), r.Bloom)
+	require.Equal(t, BytesToBloom(LogsBloom(logs)), r.Bloom)
+	for _, log := range logs {
+		require.True(t, r.Bloom.Test(log.Address.Bytes()))
+	}
+	require.Equal(t, tx.Hash(), r.TxHash)
+	require.Equal(t, uint64(7), *r.DepositNonce)
+	require.Equal(t, crypto.CreateAddress(from, 7), r.ContractAddress)
+
+	// The receipt encodes as a deposit receipt.
+	enc, err := r.MarshalBinary()
+	require.NoError(t, err)
+	require.Equal(t, byte(DepositTxType), enc[0])
+
+	// Without an effective nonce, the contract address of a creation is unknown.
+	r = ReceiptForDeposit(NewTx(&dep), ReceiptStatusSuccessful, 50_000, logs)
+	require.Nil(t, r.DepositNonce)
+	require.Equal(t, common.Address{}, r.ContractAddress)
+
+	// Calls have no contract address and deposits without an effective nonce
+	// have no deposit nonce.
+	to := common.HexToAddress("0x5678")
+	dep.To = &to
+	r = ReceiptForDeposit(NewTx(&dep), ReceiptStatusFailed, 21_000, nil)
+	require.Equal(t, ReceiptStatusFailed, r.Status)
+	require.Equal(t, Bloom{}, r.Bloom)
+	require.Nil(t, r.DepositNonce)
+	require.Equal(t, common.Address{}, r.ContractAddress)
+
+	require.Nil(t, ReceiptForDeposit(NewTx(&LegacyTx{}), ReceiptStatusSuccessful, 21_000, nil))
+}
+
 func TestRoundTripReceipt(t *testing.T) {
 	tests := []struct {
 		name string
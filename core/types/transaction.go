@@ -17,9 +17,12 @@
 package types
 
 import (
+	"bufio"
 	"bytes"
+	"compress/gzip"
 	"container/heap"
 	"errors"
+	"fmt"
 	"io"
 	"math/big"
 	"sync/atomic"
@@ -29,6 +32,7 @@ import (
 	"github.com/ethereum/go-ethereum/common/math"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rlp"
 )
 
@@ -39,6 +43,14 @@ var (
 	ErrTxTypeNotSupported   = errors.New("transaction type not supported")
 	ErrGasFeeCapTooLow      = errors.New("fee cap less than base fee")
 	errShortTypedTx         = errors.New("typed transaction too short")
+
+	// ErrTxTypeInPayload is returned when decoding a typed transaction whose type
+	// byte was encoded as the first element of the RLP payload list.
+	ErrTxTypeInPayload = errors.New("transaction type byte must precede the RLP payload")
+
+	// ErrTxNonCanonical is returned by UnmarshalBinaryStrict for transactions
+	// which decode, but do not re-encode to the same bytes.
+	ErrTxNonCanonical = errors.New("non-canonical transaction encoding")
 )
 
 // Transaction types.
@@ -137,6 +149,25 @@ func (tx *Transaction) MarshalBinary() ([]byte, error) {
 	return buf.Bytes(), err
 }
 
+// MarshalBinaryWithoutSidecar returns the consensus encoding of the transaction,
+// which never includes the blobs, commitments and proofs of a blob transaction.
+// The sidecar is not part of the encoding returned by MarshalBinary either, so
+// the two are currently equivalent, but storage code that depends on the
+// consensus form should use this method.
+func (tx *Transaction) MarshalBinaryWithoutSidecar() ([]byte, error) {
+	return tx.WithoutBlobSidecar().MarshalBinary()
+}
+
+// EncodeInto writes the canonical encoding of the transaction, as returned by
+// MarshalBinary, into buf. The buffer is not reset beforehand, which allows
+// callers to reuse buffers (e.g. from a sync.Pool) across many transactions.
+func (tx *Transaction) EncodeInto(buf *bytes.Buffer) error {
+	if tx.Type() == LegacyTxType {
+		return rlp.Encode(buf, tx.inner)
+	}
+	return tx.encodeTyped(buf)
+}
+
 // DecodeRLP implements rlp.Decoder
 func (tx *Transaction) DecodeRLP(s *rlp.Stream) error {
 	kind, size, err := s.Kind()
@@ -146,11 +177,10 @@ func (tx *Transaction) DecodeRLP(s *rlp.Stream) error {
 	case kind == rlp.List:
 		// It's a legacy transaction.
 		var inner LegacyTx
-		err := s.Decode(&inner)
-		if err == nil {
-			tx.setDecoded(&inner, rlp.ListSize(size))
+		if err := s.Decode(&inner); err != nil {
+			return err
 		}
-		return err
+		return tx.commitDecoded(&inner, rlp.ListSize(size))
 	default:
 		// It's an EIP-2718 typed TX envelope.
 		var b []byte
@@ -158,32 +188,157 @@ func (tx *Transaction) DecodeRLP(s *rlp.Stream) error {
 			return err
 		}
 		inner, err := tx.decodeTyped(b)
-		if err == nil {
-			tx.setDecoded(inner, uint64(len(b)))
+		if err != nil {
+			return err
 		}
-		return err
+		return tx.commitDecoded(inner, uint64(len(b)))
+	}
+}
+
+// DecodeTransactionRLP decodes a single transaction from an RLP stream. Legacy
+// transactions are read as an RLP list, typed transactions as an RLP string
+// holding the EIP-2718 envelope.
+func DecodeTransactionRLP(s *rlp.Stream) (*Transaction, error) {
+	tx := new(Transaction)
+	if err := tx.DecodeRLP(s); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+// DecodeTransactionsRLP decodes an RLP list of transactions from a stream one
+// element at a time. If an element fails to decode, the returned error reports
+// its index within the list.
+func DecodeTransactionsRLP(s *rlp.Stream) ([]*Transaction, error) {
+	if _, err := s.List(); err != nil {
+		return nil, err
+	}
+	var txs []*Transaction
+	for i := 0; ; i++ {
+		tx, err := DecodeTransactionRLP(s)
+		if err == rlp.EOL {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("transaction %d: %w", i, err)
+		}
+		txs = append(txs, tx)
 	}
+	if err := s.ListEnd(); err != nil {
+		return nil, err
+	}
+	return txs, nil
+}
+
+// MaxGzipTxBatchBytes is the maximum decompressed size of a batch accepted by
+// DecodeTransactionsGzip, guarding against decompression bombs. Zero disables
+// the check.
+var MaxGzipTxBatchBytes int64 = 128 * 1024 * 1024
+
+// ErrTxBatchTooLarge is returned by DecodeTransactionsGzip if the decompressed
+// batch exceeds MaxGzipTxBatchBytes.
+var ErrTxBatchTooLarge = errors.New("transaction batch too large")
+
+// DecodeTransactionsGzip decodes a gzip-compressed RLP list of transactions
+// like DecodeTransactionsRLP, decompressing the input as it is read. The
+// decompressed stream must hold exactly the list.
+func DecodeTransactionsGzip(r io.Reader) ([]*Transaction, error) {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+
+	var lr io.Reader = zr
+	if MaxGzipTxBatchBytes > 0 {
+		lr = &batchLimitReader{r: zr, n: MaxGzipTxBatchBytes, max: MaxGzipTxBatchBytes}
+	}
+	// The stream buffers its input, so use a shared buffer to be able to check
+	// for trailing data afterwards.
+	in := bufio.NewReader(lr)
+	txs, err := DecodeTransactionsRLP(rlp.NewStream(in, 0))
+	if err != nil {
+		return nil, err
+	}
+	// Read to the end, which also verifies the gzip checksum.
+	if n, err := io.Copy(io.Discard, in); err != nil {
+		return nil, err
+	} else if n > 0 {
+		return nil, fmt.Errorf("%d bytes of trailing data after transaction batch", n)
+	}
+	return txs, nil
+}
+
+// batchLimitReader reads at most n bytes from r, and fails with
+// ErrTxBatchTooLarge on any attempt to read beyond that.
+type batchLimitReader struct {
+	r      io.Reader
+	n, max int64
+}
+
+func (l *batchLimitReader) Read(p []byte) (int, error) {
+	if l.n <= 0 {
+		// Only fail if there actually is more data.
+		var b [1]byte
+		if n, err := l.r.Read(b[:]); n == 0 {
+			return 0, err
+		}
+		return 0, fmt.Errorf("%w: more than %d bytes", ErrTxBatchTooLarge, l.max)
+	}
+	if int64(len(p)) > l.n {
+		p = p[:l.n]
+	}
+	n, err := l.r.Read(p)
+	l.n -= int64(n)
+	return n, err
 }
 
 // UnmarshalBinary decodes the canonical encoding of transactions.
-// It supports legacy RLP transactions and EIP2718 typed transactions.
+// It supports legacy RLP transactions and EIP2718 typed transactions. Typed
+// transactions with the type byte inside the payload list are rejected with
+// ErrTxTypeInPayload.
 func (tx *Transaction) UnmarshalBinary(b []byte) error {
 	if len(b) > 0 && b[0] > 0x7f {
 		// It's a legacy transaction.
 		var data LegacyTx
 		err := rlp.DecodeBytes(b, &data)
 		if err != nil {
+			if tx.isTypeInPayload(b) {
+				return ErrTxTypeInPayload
+			}
 			return err
 		}
-		tx.setDecoded(&data, uint64(len(b)))
-		return nil
+		return tx.commitDecoded(&data, uint64(len(b)))
 	}
 	// It's an EIP2718 typed transaction envelope.
 	inner, err := tx.decodeTyped(b)
 	if err != nil {
 		return err
 	}
-	tx.setDecoded(inner, uint64(len(b)))
+	return tx.commitDecoded(inner, uint64(len(b)))
+}
+
+// UnmarshalBinaryStrict decodes the canonical encoding of a transaction like
+// UnmarshalBinary, but additionally requires the input to be exactly what
+// MarshalBinary produces for the decoded transaction. Input that decodes but
+// re-encodes differently is rejected with ErrTxNonCanonical.
+//
+// For the built-in transaction types, the RLP decoder already rejects all
+// non-canonical input, such as integers with leading zero bytes, with its own
+// errors. The re-encoding check matters for types registered with
+// RegisterTxType, whose decoders may accept more than one encoding.
+func (tx *Transaction) UnmarshalBinaryStrict(b []byte) error {
+	var dec Transaction
+	if err := dec.UnmarshalBinary(b); err != nil {
+		return err
+	}
+	enc, err := dec.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(enc, b) {
+		return fmt.Errorf("%w: re-encoding differs from input", ErrTxNonCanonical)
+	}
+	tx.setDecoded(dec.inner, uint64(len(b)))
 	return nil
 }
 
@@ -192,26 +347,66 @@ func (tx *Transaction) decodeTyped(b []byte) (TxData, error) {
 	if len(b) <= 1 {
 		return nil, errShortTypedTx
 	}
-	switch b[0] {
-	case AccessListTxType:
-		var inner AccessListTx
-		err := rlp.DecodeBytes(b[1:], &inner)
-		return &inner, err
-	case DynamicFeeTxType:
-		var inner DynamicFeeTx
-		err := rlp.DecodeBytes(b[1:], &inner)
-		return &inner, err
-	case BlobTxType:
-		var inner BlobTx
-		err := rlp.DecodeBytes(b[1:], &inner)
-		return &inner, err
-	case DepositTxType:
-		var inner DepositTx
-		err := rlp.DecodeBytes(b[1:], &inner)
-		return &inner, err
-	default:
+	// Legacy transactions are never wrapped in a typed envelope.
+	codec, ok := txTypeCodecs[b[0]]
+	if b[0] == LegacyTxType || !ok {
 		return nil, ErrTxTypeNotSupported
 	}
+	inner := codec.factory()
+	if err := rlp.DecodeBytes(b[1:], inner); err != nil {
+		return nil, err
+	}
+	if v, ok := inner.(interface{ validate() error }); ok {
+		return inner, v.validate()
+	}
+	return inner, nil
+}
+
+// isTypeInPayload reports whether the RLP list b, which failed to decode as a
+// legacy transaction, is a typed transaction with the type byte moved into the
+// payload list, as produced by some faulty encoders.
+func (tx *Transaction) isTypeInPayload(b []byte) bool {
+	elems, _, err := rlp.SplitList(b)
+	if err != nil {
+		return false
+	}
+	kind, typ, rest, err := rlp.Split(elems)
+	if err != nil || kind != rlp.Byte || typ[0] == LegacyTxType {
+		return false
+	}
+	buf := rlp.NewEncoderBuffer(nil)
+	l := buf.List()
+	buf.Write(rest)
+	buf.ListEnd(l)
+	_, err = tx.decodeTyped(append([]byte{typ[0]}, buf.ToBytes()...))
+	return err == nil
+}
+
+// PostDecodeHook, if set, is called with every transaction decoded from its
+// binary or JSON encoding, after the built-in checks have passed. It allows
+// embedders to apply chain-specific validation or normalization. If the hook
+// returns an error, decoding fails with that error and the receiver of the
+// decode call is left unchanged.
+//
+// The hook runs for every decoded transaction, including those of block bodies
+// read from the database or received from peers. A hook which rejects
+// transactions already stored in the database makes those blocks unreadable.
+var PostDecodeHook func(*Transaction) error
+
+// commitDecoded runs PostDecodeHook on a transaction holding the freshly
+// decoded inner transaction and, if it passes, sets the inner transaction and
+// size of tx.
+func (tx *Transaction) commitDecoded(inner TxData, size uint64) error {
+	if PostDecodeHook != nil {
+		dec := new(Transaction)
+		dec.setDecoded(inner, size)
+		if err := PostDecodeHook(dec); err != nil {
+			return err
+		}
+		inner = dec.inner
+	}
+	tx.setDecoded(inner, size)
+	return nil
 }
 
 // setDecoded sets the inner transaction and size after decoding.
@@ -249,6 +444,72 @@ func sanityCheckSignature(v *big.Int, r *big.Int, s *big.Int, maybeProtected boo
 	return nil
 }
 
+// ValidForConfig checks whether the type of the transaction is enabled by the
+// given chain configuration for a block with the given timestamp. Blob
+// transactions require Cancun, and deposits require an Optimism chain with a
+// Bedrock block. Types introduced by block number based forks (Berlin and
+// London) can only be checked for being scheduled at all, as the block number
+// is not known.
+func (tx *Transaction) ValidForConfig(config *params.ChainConfig, blockTime uint64) error {
+	switch tx.Type() {
+	case LegacyTxType:
+		return nil
+	case AccessListTxType:
+		if config.BerlinBlock == nil {
+			return fmt.Errorf("%w: access list transactions require Berlin, which is not scheduled", ErrTxTypeNotSupported)
+		}
+	case DynamicFeeTxType:
+		if config.LondonBlock == nil {
+			return fmt.Errorf("%w: dynamic fee transactions require London, which is not scheduled", ErrTxTypeNotSupported)
+		}
+	case BlobTxType:
+		if config.LondonBlock == nil || config.CancunTime == nil || *config.CancunTime > blockTime {
+			return fmt.Errorf("%w: blob transactions require Cancun, which is not active at time %d", ErrTxTypeNotSupported, blockTime)
+		}
+	case DepositTxType:
+		if !config.IsOptimism() || config.BedrockBlock == nil {
+			return fmt.Errorf("%w: deposit transactions require an Optimism Bedrock chain", ErrTxTypeNotSupported)
+		}
+	default:
+		return fmt.Errorf("%w: unknown transaction type %d", ErrTxTypeNotSupported, tx.Type())
+	}
+	return nil
+}
+
+// Validate checks the structural invariants of the transaction, independently
+// of whether it was decoded from JSON, its binary encoding or built locally:
+//
+//   - deposits must have a non-zero source hash and sender,
+//   - blob versioned hashes must carry the KZG commitment version byte,
+//   - signatures, when present, must have valid values. Typed transactions
+//     must additionally use the lower half of the curve order for s.
+func (tx *Transaction) Validate() error {
+	switch itx := tx.inner.(type) {
+	case *DepositTx:
+		return itx.validate()
+	case *depositTxWithNonce:
+		return itx.DepositTx.validate()
+	case *BlobTx:
+		for i, h := range itx.BlobHashes {
+			if h[0] != blobCommitmentVersionKZG {
+				return fmt.Errorf("blob versioned hash %d has invalid version %#x", i, h[0])
+			}
+		}
+	}
+	v, r, s := tx.RawSignatureValues()
+	if v.Sign() == 0 && r.Sign() == 0 && s.Sign() == 0 {
+		// Unsigned transaction.
+		return nil
+	}
+	if tx.Type() == LegacyTxType {
+		return sanityCheckSignature(v, r, s, true)
+	}
+	if v.BitLen() > 8 || !crypto.ValidateSignatureValues(byte(v.Uint64()), r, s, true) {
+		return ErrInvalidSig
+	}
+	return nil
+}
+
 func isProtectedV(V *big.Int) bool {
 	if V.BitLen() <= 8 {
 		v := V.Uint64()
@@ -273,6 +534,43 @@ func (tx *Transaction) Type() uint8 {
 	return tx.inner.txType()
 }
 
+// HasTypePrefix reports whether the transaction is encoded as an EIP-2718 typed
+// envelope with a leading type byte. Only legacy transactions are not.
+func (tx *Transaction) HasTypePrefix() bool {
+	return tx.Type() != LegacyTxType
+}
+
+// TxKind classifies transactions by their type.
+type TxKind int
+
+const (
+	KindUnknown TxKind = iota // unregistered or custom transaction type
+	KindLegacy
+	KindAccessList
+	KindDynamicFee
+	KindBlob
+	KindDeposit
+)
+
+// Kind returns the kind of the transaction, or KindUnknown if its type is not
+// one of the built-in transaction types.
+func (tx *Transaction) Kind() TxKind {
+	switch tx.Type() {
+	case LegacyTxType:
+		return KindLegacy
+	case AccessListTxType:
+		return KindAccessList
+	case DynamicFeeTxType:
+		return KindDynamicFee
+	case BlobTxType:
+		return KindBlob
+	case DepositTxType:
+		return KindDeposit
+	default:
+		return KindUnknown
+	}
+}
+
 // ChainId returns the EIP155 chain ID of the transaction. The return value will always be
 // non-nil. For legacy transactions which are not replay-protected, the return value is
 // zero.
@@ -286,9 +584,22 @@ func (tx *Transaction) Data() []byte { return tx.inner.data() }
 // AccessList returns the access list of the transaction.
 func (tx *Transaction) AccessList() AccessList { return tx.inner.accessList() }
 
+// AccessListGas returns the intrinsic gas charged for the access list of the
+// transaction as defined by EIP-2930, zero for types without an access list.
+func (tx *Transaction) AccessListGas() uint64 {
+	al := tx.inner.accessList()
+	return uint64(len(al))*params.TxAccessListAddressGas + uint64(al.StorageKeys())*params.TxAccessListStorageKeyGas
+}
+
 // Gas returns the gas limit of the transaction.
 func (tx *Transaction) Gas() uint64 { return tx.inner.gas() }
 
+// GasLimit returns the gas limit of the transaction, i.e. the JSON 'gas' field.
+// It is an alias of Gas. For deposits, the gas limit is bought on L1 and applies
+// to the execution on L2. Deposits do not pay for L2 gas, and system deposits
+// do not count towards the block gas limit, see CountsAgainstGasLimit.
+func (tx *Transaction) GasLimit() uint64 { return tx.inner.gas() }
+
 // GasPrice returns the gas price of the transaction.
 func (tx *Transaction) GasPrice() *big.Int { return new(big.Int).Set(tx.inner.gasPrice()) }
 
@@ -307,19 +618,126 @@ func (tx *Transaction) BlobGasFeeCap() *big.Int { return tx.inner.blobGasFeeCap(
 // BlobHashes returns the hases of the blob commitments for blob transactions, nil otherwise.
 func (tx *Transaction) BlobHashes() []common.Hash { return tx.inner.blobHashes() }
 
+// BlobTxSidecar returns the sidecar of a blob transaction, nil otherwise.
+func (tx *Transaction) BlobTxSidecar() *BlobTxSidecar {
+	if blobtx, ok := tx.inner.(*BlobTx); ok {
+		return blobtx.Sidecar
+	}
+	return nil
+}
+
+// WithBlobSidecar returns a copy of the blob transaction with the given sidecar
+// attached, replacing any existing one. The sidecar must contain a blob,
+// commitment and proof for each blob versioned hash of the transaction, with
+// the commitments matching the hashes. As the sidecar is not part of the
+// consensus encoding, the transaction hash is unaffected.
+func (tx *Transaction) WithBlobSidecar(sidecar *BlobTxSidecar) (*Transaction, error) {
+	blobtx, ok := tx.inner.(*BlobTx)
+	if !ok {
+		return nil, fmt.Errorf("%w: transaction type %d has no blob sidecar", ErrTxTypeNotSupported, tx.Type())
+	}
+	if sidecar == nil {
+		return nil, errors.New("missing blob sidecar")
+	}
+	n := len(blobtx.BlobHashes)
+	if len(sidecar.Blobs) != n || len(sidecar.Commitments) != n || len(sidecar.Proofs) != n {
+		return nil, fmt.Errorf("invalid blob sidecar: have %d blobs, %d commitments and %d proofs for %d blob hashes",
+			len(sidecar.Blobs), len(sidecar.Commitments), len(sidecar.Proofs), n)
+	}
+	for i, h := range sidecar.BlobHashes() {
+		if h != blobtx.BlobHashes[i] {
+			return nil, fmt.Errorf("invalid blob sidecar: commitment %d has versioned hash %v, want %v", i, h, blobtx.BlobHashes[i])
+		}
+	}
+	cpy := *blobtx
+	cpy.Sidecar = sidecar
+	return &Transaction{inner: &cpy, time: tx.time}, nil
+}
+
+// WithoutBlobSidecar returns a copy of the blob transaction without its sidecar.
+// Transactions which do not carry a sidecar are returned unchanged.
+func (tx *Transaction) WithoutBlobSidecar() *Transaction {
+	blobtx, ok := tx.inner.(*BlobTx)
+	if !ok || blobtx.Sidecar == nil {
+		return tx
+	}
+	cpy := *blobtx
+	cpy.Sidecar = nil
+	return &Transaction{inner: &cpy, time: tx.time}
+}
+
+// Anonymize returns a copy of the transaction for sharing in traces, with the
+// calldata zeroed and all addresses, storage keys and deposit source hashes
+// replaced by zero values. The type, gas, value and signature values are kept,
+// as are the lengths of all fields, so that the encoded size is unchanged. The
+// blob sidecar is dropped.
+func (tx *Transaction) Anonymize() *Transaction {
+	inner := tx.inner.copy()
+	switch itx := inner.(type) {
+	case *LegacyTx:
+		itx.To = anonymizeAddress(itx.To)
+		itx.Data = make([]byte, len(itx.Data))
+	case *AccessListTx:
+		itx.To = anonymizeAddress(itx.To)
+		itx.Data = make([]byte, len(itx.Data))
+		itx.AccessList = anonymizeAccessList(itx.AccessList)
+	case *DynamicFeeTx:
+		itx.To = anonymizeAddress(itx.To)
+		itx.Data = make([]byte, len(itx.Data))
+		itx.AccessList = anonymizeAccessList(itx.AccessList)
+	case *BlobTx:
+		itx.To = anonymizeAddress(itx.To)
+		itx.Data = make([]byte, len(itx.Data))
+		itx.AccessList = anonymizeAccessList(itx.AccessList)
+		itx.Sidecar = nil
+	case *DepositTx:
+		itx.SourceHash = common.Hash{}
+		itx.From = common.Address{}
+		itx.To = anonymizeAddress(itx.To)
+		itx.Data = make([]byte, len(itx.Data))
+		if wn, ok := tx.inner.(*depositTxWithNonce); ok {
+			inner = &depositTxWithNonce{DepositTx: *itx, EffectiveNonce: wn.EffectiveNonce}
+		}
+	}
+	return &Transaction{inner: inner, time: tx.time}
+}
+
+// anonymizeAddress returns a zero address if a is non-nil, and nil otherwise.
+func anonymizeAddress(a *common.Address) *common.Address {
+	if a == nil {
+		return nil
+	}
+	return new(common.Address)
+}
+
+// anonymizeAccessList returns an access list of the same shape as al, with all
+// addresses and storage keys zeroed.
+func anonymizeAccessList(al AccessList) AccessList {
+	if al == nil {
+		return nil
+	}
+	cpy := make(AccessList, len(al))
+	for i, tuple := range al {
+		cpy[i].StorageKeys = make([]common.Hash, len(tuple.StorageKeys))
+	}
+	return cpy
+}
+
 // Value returns the ether amount of the transaction.
 func (tx *Transaction) Value() *big.Int { return new(big.Int).Set(tx.inner.value()) }
 
 // Nonce returns the sender account nonce of the transaction.
 func (tx *Transaction) Nonce() uint64 { return tx.inner.nonce() }
 
+// txWithEffectiveNonce is implemented by inner transactions which carry an
+// effective nonce separate from their nonce field.
+type txWithEffectiveNonce interface {
+	effectiveNonce() *uint64
+}
+
 // EffectiveNonce returns the nonce that was actually used as part of transaction execution
 // Returns nil if the effective nonce is not known
 func (tx *Transaction) EffectiveNonce() *uint64 {
-	type txWithEffectiveNonce interface {
-		effectiveNonce() *uint64
-	}
-
 	if itx, ok := tx.inner.(txWithEffectiveNonce); ok {
 		return itx.effectiveNonce()
 	}
@@ -327,12 +745,54 @@ func (tx *Transaction) EffectiveNonce() *uint64 {
 	return &nonce
 }
 
+// HasEffectiveNonce reports whether the transaction carries an effective nonce
+// separate from its nonce field, which is the case for deposits decoded from
+// JSON with a 'nonce' field.
+func (tx *Transaction) HasEffectiveNonce() bool {
+	itx, ok := tx.inner.(txWithEffectiveNonce)
+	return ok && itx.effectiveNonce() != nil
+}
+
 // To returns the recipient address of the transaction.
 // For contract-creation transactions, To returns nil.
 func (tx *Transaction) To() *common.Address {
 	return copyAddressPtr(tx.inner.to())
 }
 
+// IsCreation returns true if the transaction creates a contract, i.e. if it has
+// no recipient. This holds for all transaction types, including deposits.
+func (tx *Transaction) IsCreation() bool {
+	return tx.inner.to() == nil
+}
+
+// ReferencedAddresses returns all addresses statically referenced by the
+// transaction: the sender of deposit transactions, the recipient and the
+// addresses of the access list, in this order. Each address is included once,
+// at the position of its first occurrence. The sender of signed transactions is
+// not included, as it is only known after signature recovery.
+func (tx *Transaction) ReferencedAddresses() []common.Address {
+	var (
+		addrs []common.Address
+		seen  = make(map[common.Address]bool)
+	)
+	add := func(addr common.Address) {
+		if !seen[addr] {
+			seen[addr] = true
+			addrs = append(addrs, addr)
+		}
+	}
+	if dep, ok := tx.depositTx(); ok {
+		add(dep.From)
+	}
+	if to := tx.inner.to(); to != nil {
+		add(*to)
+	}
+	for _, tuple := range tx.inner.accessList() {
+		add(tuple.Address)
+	}
+	return addrs
+}
+
 // SourceHash returns the hash that uniquely identifies the source of the deposit tx,
 // e.g. a user deposit event, or a L1 info deposit included in a specific L2 block height.
 // Non-deposit transactions return a zeroed hash.
@@ -346,10 +806,31 @@ func (tx *Transaction) SourceHash() common.Hash {
 // Mint returns the ETH to mint in the deposit tx.
 // This returns nil if there is nothing to mint, or if this is not a deposit tx.
 func (tx *Transaction) Mint() *big.Int {
-	if dep, ok := tx.inner.(*DepositTx); ok {
-		return dep.Mint
+	mint, _ := tx.DepositMint()
+	return mint
+}
+
+// DepositMint returns the ETH to mint in the deposit tx, and whether the
+// transaction is a deposit tx at all. The amount is nil for deposits that do
+// not mint anything.
+func (tx *Transaction) DepositMint() (*big.Int, bool) {
+	dep, ok := tx.depositTx()
+	if !ok {
+		return nil, false
 	}
-	return nil
+	return dep.Mint, true
+}
+
+// depositTx returns the inner deposit transaction, including deposits decoded
+// with an effective nonce, and whether the transaction is a deposit at all.
+func (tx *Transaction) depositTx() (*DepositTx, bool) {
+	switch itx := tx.inner.(type) {
+	case *DepositTx:
+		return itx, true
+	case *depositTxWithNonce:
+		return &itx.DepositTx, true
+	}
+	return nil, false
 }
 
 // IsDepositTx returns true if the transaction is a deposit tx type.
@@ -363,6 +844,12 @@ func (tx *Transaction) IsSystemTx() bool {
 	return tx.inner.isSystemTx()
 }
 
+// CountsAgainstGasLimit reports whether the gas limit of the transaction counts
+// towards the block gas limit, which is the case for all but system deposits.
+func (tx *Transaction) CountsAgainstGasLimit() bool {
+	return !tx.inner.isSystemTx()
+}
+
 // Cost returns (gas * gasPrice) + (blobGas * blobGasPrice) + value.
 func (tx *Transaction) Cost() *big.Int {
 	total := new(big.Int).Mul(tx.GasPrice(), new(big.Int).SetUint64(tx.Gas()))
@@ -373,6 +860,16 @@ func (tx *Transaction) Cost() *big.Int {
 	return total
 }
 
+// ValueDelta returns the value of the transaction plus, for deposits, the amount
+// minted on L2. Unlike Cost, it does not include any fees.
+func (tx *Transaction) ValueDelta() *big.Int {
+	delta := tx.Value()
+	if mint, _ := tx.DepositMint(); mint != nil {
+		delta.Add(delta, mint)
+	}
+	return delta
+}
+
 // RollupDataGas is the amount of gas it takes to confirm the tx on L1 as a rollup
 func (tx *Transaction) RollupDataGas() RollupGasData {
 	if tx.Type() == DepositTxType {
@@ -381,6 +878,12 @@ func (tx *Transaction) RollupDataGas() RollupGasData {
 	if v := tx.rollupGas.Load(); v != nil {
 		return v.(RollupGasData)
 	}
+	out := tx.computeRollupGas()
+	tx.rollupGas.Store(out)
+	return out
+}
+
+func (tx *Transaction) computeRollupGas() RollupGasData {
 	data, err := tx.MarshalBinary()
 	if err != nil { // Silent error, invalid txs will not be marshalled/unmarshalled for batch submission anyway.
 		log.Error("failed to encode tx for L1 cost computation", "err", err)
@@ -393,11 +896,21 @@ func (tx *Transaction) RollupDataGas() RollupGasData {
 			out.Ones++
 		}
 	}
-	tx.rollupGas.Store(out)
 	return out
 }
 
+// L1DataGas returns the number of zero and non-zero bytes in the canonical
+// encoding of the transaction, including the type byte of typed transactions,
+// from which the L1 data fee is computed. Deposits are not charged a data fee,
+// so both counts are zero for them.
+func (tx *Transaction) L1DataGas() (zeroBytes, nonZeroBytes uint64) {
+	gas := tx.RollupDataGas()
+	return gas.Zeroes, gas.Ones
+}
+
 // RawSignatureValues returns the V, R, S signature values of the transaction.
+// For typed transactions V is the y-parity of the signature, 0 or 1. For legacy
+// transactions it is the raw value, i.e. 27/28 or the EIP-155 form.
 // The return values should not be modified by the caller.
 func (tx *Transaction) RawSignatureValues() (v, r, s *big.Int) {
 	return tx.inner.rawSignatureValues()
@@ -423,7 +936,9 @@ func (tx *Transaction) GasTipCapIntCmp(other *big.Int) int {
 	return tx.inner.gasTipCap().Cmp(other)
 }
 
-// EffectiveGasTip returns the effective miner gasTipCap for the given base fee.
+// EffectiveGasTip returns the effective miner gasTipCap for the given base fee,
+// i.e. min(gasTipCap, gasFeeCap - baseFee). For legacy and access list
+// transactions both caps are the gas price. Deposit transactions pay no tip.
 // Note: if the effective gasTipCap is negative, this method returns both error
 // the actual negative value, _and_ ErrGasFeeCapTooLow
 func (tx *Transaction) EffectiveGasTip(baseFee *big.Int) (*big.Int, error) {
@@ -480,13 +995,76 @@ func (tx *Transaction) Hash() common.Hash {
 		return hash.(common.Hash)
 	}
 
-	var h common.Hash
+	h := tx.computeHash()
+	tx.hash.Store(h)
+	return h
+}
+
+func (tx *Transaction) computeHash() common.Hash {
 	if tx.Type() == LegacyTxType {
-		h = rlpHash(tx.inner)
-	} else {
-		h = prefixedRlpHash(tx.Type(), tx.inner)
+		return rlpHash(tx.inner)
+	}
+	return prefixedRlpHash(tx.Type(), tx.inner)
+}
+
+// Equal reports whether two transactions have the same contents. Unlike
+// comparing hashes or using reflect.DeepEqual, it does not depend on cached
+// values. The effective nonce of deposits is compared as well, the blob
+// sidecar, which is not part of the transaction contents, is not.
+func (tx *Transaction) Equal(other *Transaction) bool {
+	if tx == nil || other == nil {
+		return tx == other
 	}
+	if tx.Type() != other.Type() {
+		return false
+	}
+	if a, b := tx.EffectiveNonce(), other.EffectiveNonce(); (a == nil) != (b == nil) || (a != nil && *a != *b) {
+		return false
+	}
+	a, err := rlp.EncodeToBytes(tx.inner)
+	if err != nil {
+		return false
+	}
+	b, err := rlp.EncodeToBytes(other.inner)
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(a, b)
+}
+
+// HashExcludingNonce returns a nonce-independent identifier of the transaction.
+// It is an alias of Hash: the effective nonce attached to deposits decoded from
+// RPC responses is not part of their RLP encoding, so Hash never depends on it.
+// The method exists so that callers correlating deposits across sources state
+// that requirement explicitly, and the tests keep the two from diverging.
+func (tx *Transaction) HashExcludingNonce() common.Hash {
+	return tx.Hash()
+}
+
+// RecomputeHash recomputes the cached hash, size and sender of the transaction
+// and returns the new hash. It is only needed when the cached values may be
+// stale, e.g. after decoding into a transaction whose hash was already computed.
+//
+// The caches are overwritten with freshly computed values rather than reset, so
+// concurrent readers observe either the old or the new value.
+func (tx *Transaction) RecomputeHash() common.Hash {
+	h := tx.computeHash()
 	tx.hash.Store(h)
+	tx.size.Store(tx.computeSize())
+	if tx.Type() != DepositTxType {
+		tx.rollupGas.Store(tx.computeRollupGas())
+	}
+	if sc := tx.from.Load(); sc != nil {
+		// Re-derive the sender with the signer that was used before. If that
+		// fails, overwrite the entry with one that no signer matches.
+		var cache sigCache
+		if signer := sc.(sigCache).signer; signer != nil {
+			if addr, err := signer.Sender(tx); err == nil {
+				cache = sigCache{signer: signer, from: addr}
+			}
+		}
+		tx.from.Store(cache)
+	}
 	return h
 }
 
@@ -496,6 +1074,12 @@ func (tx *Transaction) Size() uint64 {
 	if size := tx.size.Load(); size != nil {
 		return size.(uint64)
 	}
+	size := tx.computeSize()
+	tx.size.Store(size)
+	return size
+}
+
+func (tx *Transaction) computeSize() uint64 {
 	c := writeCounter(0)
 	rlp.Encode(&c, &tx.inner)
 
@@ -503,7 +1087,6 @@ func (tx *Transaction) Size() uint64 {
 	if tx.Type() != LegacyTxType {
 		size += 1 // type byte
 	}
-	tx.size.Store(size)
 	return size
 }
 
@@ -519,6 +1102,22 @@ func (tx *Transaction) WithSignature(signer Signer, sig []byte) (*Transaction, e
 	return &Transaction{inner: cpy, time: tx.time}, nil
 }
 
+// WithSourceHash returns a new deposit transaction with the source hash replaced.
+// It returns an error if the transaction is not a deposit transaction.
+func (tx *Transaction) WithSourceHash(h common.Hash) (*Transaction, error) {
+	dep, ok := tx.depositTx()
+	if !ok {
+		return nil, fmt.Errorf("%w: transaction type %d has no source hash", ErrTxTypeNotSupported, tx.Type())
+	}
+	cpy := dep.copy().(*DepositTx)
+	cpy.SourceHash = h
+	var inner TxData = cpy
+	if wn, ok := tx.inner.(*depositTxWithNonce); ok {
+		inner = &depositTxWithNonce{DepositTx: *cpy, EffectiveNonce: wn.EffectiveNonce}
+	}
+	return &Transaction{inner: inner, time: tx.time}, nil
+}
+
 // Transactions implements DerivableList for transactions.
 type Transactions []*Transaction
 
@@ -573,6 +1172,27 @@ func HashDifference(a, b []common.Hash) []common.Hash {
 	return keep
 }
 
+// CompareForBlockOrder compares two transactions by their position in a block.
+// It returns a negative number if a must be included before b, a positive
+// number if b must be included before a, and zero if their order is undecided.
+// Deposit transactions are included before all others. Other transactions are
+// ordered by the effective gas price they pay at the given base fee, highest
+// first.
+func CompareForBlockOrder(a, b *Transaction, baseFee *big.Int) int {
+	aDeposit, bDeposit := a.IsDepositTx(), b.IsDepositTx()
+	switch {
+	case aDeposit && bDeposit:
+		return 0
+	case aDeposit:
+		return -1
+	case bDeposit:
+		return 1
+	}
+	aPrice := a.inner.effectiveGasPrice(new(big.Int), baseFee)
+	bPrice := b.inner.effectiveGasPrice(new(big.Int), baseFee)
+	return bPrice.Cmp(aPrice)
+}
+
 // TxByNonce implements the sort interface to allow sorting a list of transactions
 // by their nonces. This is usually only useful for sorting transactions from a
 // single account, otherwise a nonce comparison doesn't make much sense.
@@ -0,0 +1,137 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// txDelta is the encoding of a transaction relative to a previous transaction
+// of the same type.
+type txDelta struct {
+	Changed uint64         // bit i is set if field i differs from the previous tx
+	Fields  []rlp.RawValue // RLP encodings of the changed fields, in order
+}
+
+// EncodeTransactionDelta encodes cur relative to prev, which must be of the same
+// type. Only the fields of the inner transaction that differ from prev are
+// included. Blob sidecars are not part of the encoding.
+func EncodeTransactionDelta(prev, cur *Transaction) ([]byte, error) {
+	if prev.Type() != cur.Type() {
+		return nil, fmt.Errorf("transaction type mismatch: have %d, previous %d", cur.Type(), prev.Type())
+	}
+	prevFields, err := txFields(prev.inner)
+	if err != nil {
+		return nil, err
+	}
+	curFields, err := txFields(cur.inner)
+	if err != nil {
+		return nil, err
+	}
+	if len(prevFields) != len(curFields) {
+		return nil, fmt.Errorf("transaction field count mismatch: have %d, previous %d", len(curFields), len(prevFields))
+	}
+	if len(curFields) > 64 {
+		return nil, fmt.Errorf("too many transaction fields: %d", len(curFields))
+	}
+	var delta txDelta
+	for i := range curFields {
+		if !bytes.Equal(prevFields[i], curFields[i]) {
+			delta.Changed |= 1 << i
+			delta.Fields = append(delta.Fields, curFields[i])
+		}
+	}
+	return rlp.EncodeToBytes(&delta)
+}
+
+// DecodeTransactionDelta decodes a transaction encoded by EncodeTransactionDelta
+// relative to prev.
+func DecodeTransactionDelta(prev *Transaction, delta []byte) (*Transaction, error) {
+	var dec txDelta
+	if err := rlp.DecodeBytes(delta, &dec); err != nil {
+		return nil, err
+	}
+	fields, err := txFields(prev.inner)
+	if err != nil {
+		return nil, err
+	}
+	if dec.Changed>>len(fields) != 0 {
+		return nil, errors.New("transaction delta changes unknown fields")
+	}
+	next := 0
+	for i := range fields {
+		if dec.Changed&(1<<i) == 0 {
+			continue
+		}
+		if next == len(dec.Fields) {
+			return nil, errors.New("transaction delta has too few fields")
+		}
+		fields[i] = dec.Fields[next]
+		next++
+	}
+	if next != len(dec.Fields) {
+		return nil, errors.New("transaction delta has too many fields")
+	}
+	enc, err := rlp.EncodeToBytes(fields)
+	if err != nil {
+		return nil, err
+	}
+	codec, ok := txTypeCodecs[prev.Type()]
+	if !ok {
+		return nil, ErrTxTypeNotSupported
+	}
+	inner := codec.factory()
+	if err := rlp.DecodeBytes(enc, inner); err != nil {
+		return nil, err
+	}
+	if v, ok := inner.(interface{ validate() error }); ok {
+		if err := v.validate(); err != nil {
+			return nil, err
+		}
+	}
+	tx := new(Transaction)
+	if err := tx.commitDecoded(inner, 0); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+// txFields returns the RLP encodings of the fields of an inner transaction.
+func txFields(inner TxData) ([]rlp.RawValue, error) {
+	enc, err := rlp.EncodeToBytes(inner)
+	if err != nil {
+		return nil, err
+	}
+	content, _, err := rlp.SplitList(enc)
+	if err != nil {
+		return nil, err
+	}
+	var fields []rlp.RawValue
+	for len(content) > 0 {
+		_, _, rest, err := rlp.Split(content)
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, content[:len(content)-len(rest)])
+		content = rest
+	}
+	return fields, nil
+}
@@ -0,0 +1,86 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransactionDeltaRoundTrip(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	signer := NewLondonSigner(big.NewInt(1))
+	to := common.HexToAddress("0x2")
+	legacy := func(nonce uint64) *Transaction {
+		return MustSignNewTx(key, signer, &LegacyTx{Nonce: nonce, GasPrice: big.NewInt(1), Gas: 21000, To: &to, Value: big.NewInt(1), Data: []byte{0x01, 0x02}})
+	}
+	deposit := func(data []byte) *Transaction {
+		return NewTx(&DepositTx{SourceHash: common.HexToHash("0x1234"), From: common.HexToAddress("0x1"), To: &to, Value: big.NewInt(1), Gas: 1000, Data: data})
+	}
+	tests := []struct {
+		name      string
+		prev, cur *Transaction
+	}{
+		{name: "LegacyNonce", prev: legacy(1), cur: legacy(2)},
+		{name: "DepositData", prev: deposit([]byte{0x01}), cur: deposit([]byte{0x02, 0x03})},
+		{name: "Identical", prev: deposit([]byte{0x01}), cur: deposit([]byte{0x01})},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			delta, err := EncodeTransactionDelta(test.prev, test.cur)
+			require.NoError(t, err)
+			full, err := test.cur.MarshalBinary()
+			require.NoError(t, err)
+			require.Less(t, len(delta), len(full))
+
+			dec, err := DecodeTransactionDelta(test.prev, delta)
+			require.NoError(t, err)
+			require.Equal(t, test.cur.Type(), dec.Type())
+			require.Equal(t, test.cur.Hash(), dec.Hash())
+		})
+	}
+
+	// Transactions of different types cannot be diffed.
+	_, err := EncodeTransactionDelta(legacy(1), deposit(nil))
+	require.ErrorContains(t, err, "transaction type mismatch")
+}
+
+func TestDecodeTransactionDeltaInvalid(t *testing.T) {
+	prev := NewTx(&DepositTx{SourceHash: common.HexToHash("0x1234"), From: common.HexToAddress("0x1"), Value: big.NewInt(1), Gas: 1000})
+	tests := []struct {
+		name  string
+		delta txDelta
+		err   string
+	}{
+		{name: "UnknownField", delta: txDelta{Changed: 1 << 20, Fields: nil}, err: "changes unknown fields"},
+		{name: "TooFewFields", delta: txDelta{Changed: 0b11, Fields: nil}, err: "too few fields"},
+		{name: "TooManyFields", delta: txDelta{Changed: 0, Fields: []rlp.RawValue{{0x80}}}, err: "too many fields"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			enc, err := rlp.EncodeToBytes(&test.delta)
+			require.NoError(t, err)
+			_, err = DecodeTransactionDelta(prev, enc)
+			require.ErrorContains(t, err, test.err)
+		})
+	}
+}
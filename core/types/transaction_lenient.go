@@ -0,0 +1,289 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// lenientQuantityFields are the transaction JSON fields which the lenient
+// decoder accepts as plain JSON numbers.
+var lenientQuantityFields = []string{
+	"nonce",
+	"gas",
+	"gasPrice",
+	"value",
+	"maxPriorityFeePerGas",
+	"maxFeePerGas",
+	"maxFeePerDataGas",
+}
+
+// nonDepositFields are transaction JSON fields which never occur in deposit
+// transactions. Their presence makes a type-less object with deposit fields
+// ambiguous.
+var nonDepositFields = []string{
+	"chainId",
+	"gasPrice",
+	"maxPriorityFeePerGas",
+	"maxFeePerGas",
+	"maxFeePerDataGas",
+	"accessList",
+	"blobVersionedHashes",
+	"v",
+	"r",
+	"s",
+}
+
+// UnmarshalJSONLenient decodes a transaction from JSON like UnmarshalJSON, but
+// first normalizes the following non-standard encodings produced by some
+// third-party tooling:
+//
+//   - quantity fields given as JSON numbers rather than hex strings, including
+//     integers in scientific notation such as 1e18,
+//   - quantity fields given as hex strings with leading zero digits, e.g. 0x00a,
+//   - an empty string or zero address 'to' denoting contract creation in
+//     deposit transactions. The strict decoder treats the latter as a call to
+//     the zero address,
+//   - a missing 'type' on deposit transactions, inferred from the presence of
+//     'sourceHash' and 'from',
+//   - a 'gasPrice' on dynamic fee transactions, used for any missing fee cap.
+//     If 'maxFeePerGas' is present as well, the two must be equal,
+//   - a zero 'gasPrice' on deposit transactions in any of the above forms,
+//     e.g. "0". Non-zero values are rejected with the same error as in
+//     UnmarshalJSON, regardless of their format,
+//   - the signature given as a 'signature' array [v, r, s] instead of separate
+//     fields. Giving both forms is an error,
+//   - top-level hex string fields with an uppercase '0X' prefix or without a
+//     prefix. Strings without a prefix are always interpreted as hex.
+func (tx *Transaction) UnmarshalJSONLenient(input []byte) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(input, &fields); err != nil {
+		return err
+	}
+	if fields == nil {
+		return errTxNull
+	}
+	if err := lenientSignature(fields); err != nil {
+		return err
+	}
+	for name, raw := range fields {
+		fields[name] = lenientHexPrefix(raw)
+	}
+	var typ hexutil.Uint64
+	if raw, ok := fields["type"]; ok {
+		if err := json.Unmarshal(raw, &typ); err != nil {
+			return err
+		}
+	} else if sniffed, err := sniffTxType(fields); err != nil {
+		return err
+	} else {
+		typ = hexutil.Uint64(sniffed)
+		fields["type"], _ = json.Marshal(typ)
+	}
+	if typ == DepositTxType && lenientCreation(fields["to"]) {
+		fields["to"] = json.RawMessage("null")
+	}
+	for _, name := range lenientQuantityFields {
+		raw, ok := fields[name]
+		if !ok {
+			continue
+		}
+		norm, err := lenientQuantity(raw)
+		if err != nil {
+			return fmt.Errorf("invalid field '%s' in transaction: %w", name, err)
+		}
+		fields[name] = norm
+	}
+	switch typ {
+	case DynamicFeeTxType:
+		if err := lenientGasPrice(fields); err != nil {
+			return err
+		}
+	case DepositTxType:
+		if err := lenientDepositGasPrice(fields); err != nil {
+			return err
+		}
+	}
+	normalized, err := json.Marshal(fields)
+	if err != nil {
+		return err
+	}
+	return tx.UnmarshalJSON(normalized)
+}
+
+// lenientGasPrice fills the missing fee caps of a dynamic fee transaction from
+// its 'gasPrice' field, which some wallets include for backward compatibility.
+func lenientGasPrice(fields map[string]json.RawMessage) error {
+	raw, ok := fields["gasPrice"]
+	if !ok {
+		return nil
+	}
+	delete(fields, "gasPrice")
+	if string(raw) == "null" {
+		return nil
+	}
+	if feeCap, ok := fields["maxFeePerGas"]; ok {
+		var price, maxFee hexutil.Big
+		if err := json.Unmarshal(raw, &price); err != nil {
+			return fmt.Errorf("invalid field 'gasPrice' in transaction: %w", err)
+		}
+		if err := json.Unmarshal(feeCap, &maxFee); err != nil {
+			return fmt.Errorf("invalid field 'maxFeePerGas' in transaction: %w", err)
+		}
+		if price.ToInt().Cmp(maxFee.ToInt()) != 0 {
+			return fmt.Errorf("conflicting 'gasPrice' %d and 'maxFeePerGas' %d in transaction", price.ToInt(), maxFee.ToInt())
+		}
+		return nil
+	}
+	fields["maxFeePerGas"] = raw
+	if _, ok := fields["maxPriorityFeePerGas"]; !ok {
+		fields["maxPriorityFeePerGas"] = raw
+	}
+	return nil
+}
+
+// lenientSignature replaces a 'signature' array [v, r, s] with the separate
+// signature fields.
+func lenientSignature(fields map[string]json.RawMessage) error {
+	raw, ok := fields["signature"]
+	if !ok {
+		return nil
+	}
+	delete(fields, "signature")
+	for _, name := range []string{"v", "r", "s"} {
+		if _, ok := fields[name]; ok {
+			return fmt.Errorf("conflicting 'signature' and '%s' in transaction", name)
+		}
+	}
+	var sig []json.RawMessage
+	if err := json.Unmarshal(raw, &sig); err != nil {
+		return fmt.Errorf("invalid field 'signature' in transaction: %w", err)
+	}
+	if len(sig) != 3 {
+		return fmt.Errorf("invalid field 'signature' in transaction: have %d values, want 3", len(sig))
+	}
+	fields["v"], fields["r"], fields["s"] = sig[0], sig[1], sig[2]
+	return nil
+}
+
+// lenientCreation reports whether the 'to' field of a deposit transaction is an
+// empty string or the zero address, which some producers send for contract
+// creations.
+func lenientCreation(raw json.RawMessage) bool {
+	if string(raw) == `""` {
+		return true
+	}
+	var to common.Address
+	return len(raw) > 0 && raw[0] == '"' && json.Unmarshal(raw, &to) == nil && to == (common.Address{})
+}
+
+// lenientDepositGasPrice removes a zero 'gasPrice' from a deposit transaction.
+// The field is meaningless for deposits, and only zero is accepted.
+func lenientDepositGasPrice(fields map[string]json.RawMessage) error {
+	raw, ok := fields["gasPrice"]
+	if !ok {
+		return nil
+	}
+	delete(fields, "gasPrice")
+	if string(raw) == "null" {
+		return nil
+	}
+	var price hexutil.Big
+	if err := json.Unmarshal(raw, &price); err != nil || price.ToInt().Sign() != 0 {
+		return errors.New("deposit transaction GasPrice must be 0")
+	}
+	return nil
+}
+
+// lenientQuantity converts a quantity given as a JSON number, possibly in
+// scientific notation, into a hex string, and strips leading zero digits from
+// hex strings. Any other value is returned unchanged.
+func lenientQuantity(raw json.RawMessage) (json.RawMessage, error) {
+	if len(raw) > 0 && raw[0] == '"' {
+		var str string
+		if err := json.Unmarshal(raw, &str); err != nil || !strings.HasPrefix(str, "0x") || len(str) <= 3 {
+			return raw, nil
+		}
+		digits := strings.TrimLeft(str[2:], "0")
+		if digits == "" {
+			digits = "0"
+		}
+		return json.Marshal("0x" + digits)
+	}
+	if len(raw) == 0 || raw[0] < '0' || raw[0] > '9' {
+		return raw, nil
+	}
+	r, ok := new(big.Rat).SetString(string(raw))
+	if !ok || !r.IsInt() {
+		return nil, fmt.Errorf("non-integer number %s", raw)
+	}
+	return json.Marshal((*hexutil.Big)(r.Num()))
+}
+
+// lenientHexPrefix lowercases the '0X' prefix of a hex string, or adds a '0x'
+// prefix if it is missing. Any other value is returned unchanged.
+func lenientHexPrefix(raw json.RawMessage) json.RawMessage {
+	var str string
+	if len(raw) == 0 || raw[0] != '"' || json.Unmarshal(raw, &str) != nil || str == "" {
+		return raw
+	}
+	switch {
+	case strings.HasPrefix(str, "0x"):
+		return raw
+	case strings.HasPrefix(str, "0X"):
+		str = "0x" + str[2:]
+	case isHexString(str):
+		str = "0x" + str
+	default:
+		return raw
+	}
+	norm, _ := json.Marshal(str)
+	return norm
+}
+
+func isHexString(str string) bool {
+	for _, c := range []byte(str) {
+		if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F') {
+			return false
+		}
+	}
+	return true
+}
+
+// sniffTxType infers the type of a transaction object without a 'type' field.
+// Objects carrying both 'sourceHash' and 'from' are classified as deposits,
+// anything else is left to the legacy decoder.
+func sniffTxType(fields map[string]json.RawMessage) (byte, error) {
+	_, hasSourceHash := fields["sourceHash"]
+	_, hasFrom := fields["from"]
+	if !hasSourceHash || !hasFrom {
+		return LegacyTxType, nil
+	}
+	for _, name := range nonDepositFields {
+		if _, ok := fields[name]; ok {
+			return 0, fmt.Errorf("ambiguous transaction without 'type': has deposit fields and '%s'", name)
+		}
+	}
+	return DepositTxType, nil
+}
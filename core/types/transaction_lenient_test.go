@@ -0,0 +1,291 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnmarshalJSONLenientNumbers(t *testing.T) {
+	var (
+		hexJSON = `{"type":"0x2","chainId":"0x1","nonce":"0x7","to":"0x0000000000000000000000000000000000000001","gas":"0x5208","maxPriorityFeePerGas":"0x3b9aca00","maxFeePerGas":"0x77359400","value":"0xde0b6b3a7640000","input":"0x","v":"0x0","r":"0x0","s":"0x0"}`
+		intJSON = `{"type":"0x2","chainId":"0x1","nonce":7,"to":"0x0000000000000000000000000000000000000001","gas":21000,"maxPriorityFeePerGas":1000000000,"maxFeePerGas":2000000000,"value":1000000000000000000,"input":"0x","v":"0x0","r":"0x0","s":"0x0"}`
+	)
+	var want Transaction
+	require.NoError(t, want.UnmarshalJSON([]byte(hexJSON)))
+
+	// The strict decoder rejects plain numbers.
+	require.Error(t, new(Transaction).UnmarshalJSON([]byte(intJSON)))
+
+	var have Transaction
+	require.NoError(t, have.UnmarshalJSONLenient([]byte(intJSON)))
+	require.Equal(t, want.Hash(), have.Hash())
+
+	var same Transaction
+	require.NoError(t, same.UnmarshalJSONLenient([]byte(hexJSON)))
+	require.Equal(t, want.Hash(), same.Hash())
+}
+
+func TestUnmarshalJSONLenientLegacyNumbers(t *testing.T) {
+	var (
+		hexJSON = `{"type":"0x0","nonce":"0x1","to":null,"gas":"0x64","gasPrice":"0xa","value":"0x0","input":"0x6001","v":"0x0","r":"0x0","s":"0x0"}`
+		intJSON = `{"type":"0x0","nonce":1,"to":null,"gas":100,"gasPrice":10,"value":0,"input":"0x6001","v":"0x0","r":"0x0","s":"0x0"}`
+	)
+	var want, have Transaction
+	require.NoError(t, want.UnmarshalJSON([]byte(hexJSON)))
+	require.NoError(t, have.UnmarshalJSONLenient([]byte(intJSON)))
+	require.Equal(t, want.Hash(), have.Hash())
+
+	require.ErrorContains(t, new(Transaction).UnmarshalJSONLenient([]byte(`{"type":"0x0","nonce":1.5}`)), "invalid field 'nonce'")
+}
+
+func TestUnmarshalJSONLenientDepositCreation(t *testing.T) {
+	const depositJSON = `{"type":"0x7e",%s"gas":"0x1234","value":"0x1","input":"0x","sourceHash":"0x0000000000000000000000000000000000000000000000000000000000001234","from":"0x0000000000000000000000000000000000000001"}`
+	tests := []struct {
+		name string
+		to   string
+	}{
+		{name: "Null", to: `"to":null,`},
+		{name: "Absent", to: ``},
+		{name: "Empty string", to: `"to":"",`},
+		{name: "Zero address", to: `"to":"0x0000000000000000000000000000000000000000",`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var tx Transaction
+			require.NoError(t, tx.UnmarshalJSONLenient([]byte(fmt.Sprintf(depositJSON, test.to))))
+			require.Nil(t, tx.To())
+		})
+	}
+	// The strict decoder does not accept the empty string, and treats the zero
+	// address as a call.
+	require.Error(t, new(Transaction).UnmarshalJSON([]byte(fmt.Sprintf(depositJSON, `"to":"",`))))
+	var call Transaction
+	require.NoError(t, call.UnmarshalJSON([]byte(fmt.Sprintf(depositJSON, `"to":"0x0000000000000000000000000000000000000000",`))))
+	require.Equal(t, &common.Address{}, call.To())
+
+	// Other transaction types keep the zero address in lenient mode.
+	const legacyJSON = `{"type":"0x0","nonce":"0x0","to":"0x0000000000000000000000000000000000000000","gas":"0x5208","gasPrice":"0x1","value":"0x0","input":"0x","v":"0x0","r":"0x0","s":"0x0"}`
+	var legacy Transaction
+	require.NoError(t, legacy.UnmarshalJSONLenient([]byte(legacyJSON)))
+	require.Equal(t, &common.Address{}, legacy.To())
+}
+
+func TestUnmarshalJSONLenientSniffDeposit(t *testing.T) {
+	const (
+		typed    = `{"type":"0x7e","to":null,"gas":"0x1234","value":"0x1","input":"0x","sourceHash":"0x0000000000000000000000000000000000000000000000000000000000001234","from":"0x0000000000000000000000000000000000000001"}`
+		typeless = `{"to":null,"gas":"0x1234","value":"0x1","input":"0x","sourceHash":"0x0000000000000000000000000000000000000000000000000000000000001234","from":"0x0000000000000000000000000000000000000001"%s}`
+	)
+	var want, have Transaction
+	require.NoError(t, want.UnmarshalJSON([]byte(typed)))
+	require.NoError(t, have.UnmarshalJSONLenient([]byte(fmt.Sprintf(typeless, ""))))
+	require.Equal(t, uint8(DepositTxType), have.Type())
+	require.Equal(t, want.Hash(), have.Hash())
+
+	// The strict decoder treats the object as a legacy transaction.
+	require.Error(t, new(Transaction).UnmarshalJSON([]byte(fmt.Sprintf(typeless, ""))))
+
+	// Signature or fee fields make the object ambiguous.
+	err := new(Transaction).UnmarshalJSONLenient([]byte(fmt.Sprintf(typeless, `,"gasPrice":"0x1","v":"0x0","r":"0x0","s":"0x0"`)))
+	require.ErrorContains(t, err, "ambiguous transaction without 'type'")
+	require.ErrorContains(t, err, "'gasPrice'")
+}
+
+func TestUnmarshalJSONLenientGasPrice(t *testing.T) {
+	const dynamicJSON = `{"type":"0x2","chainId":"0x1","nonce":"0x0","to":null,"gas":"0x5208",%s"value":"0x0","input":"0x","v":"0x0","r":"0x0","s":"0x0"}`
+
+	// Only the gas price: used for both fee caps.
+	var tx Transaction
+	require.NoError(t, tx.UnmarshalJSONLenient([]byte(fmt.Sprintf(dynamicJSON, `"gasPrice":"0x64",`))))
+	require.Equal(t, uint64(100), tx.GasFeeCap().Uint64())
+	require.Equal(t, uint64(100), tx.GasTipCap().Uint64())
+
+	// Only the fee caps: unchanged.
+	require.NoError(t, tx.UnmarshalJSONLenient([]byte(fmt.Sprintf(dynamicJSON, `"maxFeePerGas":"0x64","maxPriorityFeePerGas":"0x2",`))))
+	require.Equal(t, uint64(100), tx.GasFeeCap().Uint64())
+	require.Equal(t, uint64(2), tx.GasTipCap().Uint64())
+
+	// Gas price and a tip: the tip is kept.
+	require.NoError(t, tx.UnmarshalJSONLenient([]byte(fmt.Sprintf(dynamicJSON, `"gasPrice":100,"maxPriorityFeePerGas":"0x2",`))))
+	require.Equal(t, uint64(100), tx.GasFeeCap().Uint64())
+	require.Equal(t, uint64(2), tx.GasTipCap().Uint64())
+
+	// Consistent and conflicting values.
+	require.NoError(t, tx.UnmarshalJSONLenient([]byte(fmt.Sprintf(dynamicJSON, `"gasPrice":"0x64","maxFeePerGas":"0x64","maxPriorityFeePerGas":"0x2",`))))
+	err := tx.UnmarshalJSONLenient([]byte(fmt.Sprintf(dynamicJSON, `"gasPrice":"0x65","maxFeePerGas":"0x64","maxPriorityFeePerGas":"0x2",`)))
+	require.EqualError(t, err, "conflicting 'gasPrice' 101 and 'maxFeePerGas' 100 in transaction")
+
+	// A null gas price, as emitted by MarshalJSON, is ignored.
+	require.NoError(t, tx.UnmarshalJSONLenient([]byte(fmt.Sprintf(dynamicJSON, `"gasPrice":null,"maxFeePerGas":"0x64","maxPriorityFeePerGas":"0x2",`))))
+	require.Equal(t, uint64(100), tx.GasFeeCap().Uint64())
+
+	// The strict decoder requires the fee caps.
+	require.Error(t, new(Transaction).UnmarshalJSON([]byte(fmt.Sprintf(dynamicJSON, `"gasPrice":"0x64",`))))
+}
+
+func TestUnmarshalJSONLenientHexPrefix(t *testing.T) {
+	const dynamicJSON = `{"type":"0x2","chainId":"0x1","nonce":"0x0","to":"%s","gas":"0x5208","maxPriorityFeePerGas":"0x1","maxFeePerGas":"0x1","value":"%s","input":"%s","v":"0x0","r":"0x0","s":"0x0"}`
+	var want Transaction
+	require.NoError(t, want.UnmarshalJSON([]byte(fmt.Sprintf(dynamicJSON, "0x00000000000000000000000000000000000000ab", "0xde0b6b3a7640000", "0x6001"))))
+
+	tests := []struct {
+		name             string
+		to, value, input string
+	}{
+		{name: "Missing prefix", to: "00000000000000000000000000000000000000ab", value: "de0b6b3a7640000", input: "6001"},
+		{name: "Uppercase prefix", to: "0X00000000000000000000000000000000000000ab", value: "0XDE0B6B3A7640000", input: "0X6001"},
+		{name: "Mixed", to: "0x00000000000000000000000000000000000000AB", value: "de0b6b3a7640000", input: "0X6001"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			input := []byte(fmt.Sprintf(dynamicJSON, test.to, test.value, test.input))
+			var have Transaction
+			require.NoError(t, have.UnmarshalJSONLenient(input))
+			require.Equal(t, want.Hash(), have.Hash())
+		})
+	}
+
+	// The strict decoder requires the prefix.
+	require.Error(t, new(Transaction).UnmarshalJSON([]byte(fmt.Sprintf(dynamicJSON, tests[0].to, tests[0].value, tests[0].input))))
+
+	// Non-hex strings are left for the standard decoder to reject.
+	err := new(Transaction).UnmarshalJSONLenient([]byte(fmt.Sprintf(dynamicJSON, "0x00000000000000000000000000000000000000ab", "0x1", "xyz")))
+	require.ErrorContains(t, err, "hex string without 0x prefix")
+}
+
+func TestUnmarshalJSONDepositGasPrice(t *testing.T) {
+	const depositJSON = `{"type":"0x7e",%s"to":null,"gas":"0x1234","value":"0x1","input":"0x","sourceHash":"0x0000000000000000000000000000000000000000000000000000000000001234","from":"0x0000000000000000000000000000000000000001"}`
+	tests := []struct {
+		name      string
+		gasPrice  string
+		strictErr string
+		err       string
+	}{
+		{name: "Omitted", gasPrice: ``},
+		{name: "Null", gasPrice: `"gasPrice":null,`},
+		{name: "Hex zero", gasPrice: `"gasPrice":"0x0",`},
+		{name: "Number zero", gasPrice: `"gasPrice":0,`, strictErr: "cannot unmarshal non-string"},
+		{name: "String zero", gasPrice: `"gasPrice":"0",`, strictErr: "hex string without 0x prefix"},
+		{name: "Hex non-zero", gasPrice: `"gasPrice":"0x1",`, strictErr: "deposit transaction GasPrice must be 0", err: "deposit transaction GasPrice must be 0"},
+		{name: "String non-zero", gasPrice: `"gasPrice":"1",`, strictErr: "hex string without 0x prefix", err: "deposit transaction GasPrice must be 0"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			input := []byte(fmt.Sprintf(depositJSON, test.gasPrice))
+
+			var strict Transaction
+			if err := strict.UnmarshalJSON(input); test.strictErr != "" {
+				require.ErrorContains(t, err, test.strictErr)
+			} else {
+				require.NoError(t, err)
+				require.Zero(t, strict.GasPrice().Sign())
+			}
+
+			var lenient Transaction
+			if err := lenient.UnmarshalJSONLenient(input); test.err != "" {
+				require.EqualError(t, err, test.err)
+			} else {
+				require.NoError(t, err)
+				require.Zero(t, lenient.GasPrice().Sign())
+			}
+		})
+	}
+}
+
+func TestUnmarshalJSONLenientPaddedHex(t *testing.T) {
+	const legacyJSON = `{"type":"0x0","nonce":"0x0","to":null,"gas":"0x5208","gasPrice":"%s","value":%s,"input":"0x","v":"0x0","r":"0x0","s":"0x0"}`
+	tests := []struct {
+		name            string
+		gasPrice, value string
+		wantGasPrice    int64
+		wantValue       string
+	}{
+		{name: "Padded", gasPrice: "0x0001", value: `"0x00a"`, wantGasPrice: 1, wantValue: "10"},
+		{name: "Padded zero", gasPrice: "0x00", value: `"0x0000"`, wantGasPrice: 0, wantValue: "0"},
+		{name: "Scientific notation", gasPrice: "0x1", value: `1e18`, wantGasPrice: 1, wantValue: "1000000000000000000"},
+		{name: "Scientific notation with fraction", gasPrice: "0x1", value: `1.5E3`, wantGasPrice: 1, wantValue: "1500"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			input := []byte(fmt.Sprintf(legacyJSON, test.gasPrice, test.value))
+			require.Error(t, new(Transaction).UnmarshalJSON(input))
+
+			var tx Transaction
+			require.NoError(t, tx.UnmarshalJSONLenient(input))
+			require.Equal(t, test.wantGasPrice, tx.GasPrice().Int64())
+			require.Equal(t, test.wantValue, tx.Value().String())
+		})
+	}
+
+	err := new(Transaction).UnmarshalJSONLenient([]byte(fmt.Sprintf(legacyJSON, "0x1", `1.5e-1`)))
+	require.ErrorContains(t, err, "invalid field 'value'")
+}
+
+func TestUnmarshalJSONLenientSignatureArray(t *testing.T) {
+	key, _ := crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	signer := NewLondonSigner(big.NewInt(1))
+	tests := []struct {
+		name string
+		tx   TxData
+	}{
+		{name: "Legacy", tx: &LegacyTx{Nonce: 1, GasPrice: big.NewInt(10), Gas: 21000, To: &testAddr, Value: big.NewInt(1)}},
+		{name: "DynamicFee", tx: &DynamicFeeTx{ChainID: big.NewInt(1), Nonce: 1, GasTipCap: big.NewInt(1), GasFeeCap: big.NewInt(10), Gas: 21000, To: &testAddr, Value: big.NewInt(1)}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			want, err := SignNewTx(key, signer, test.tx)
+			require.NoError(t, err)
+			enc, err := json.Marshal(want)
+			require.NoError(t, err)
+
+			var fields map[string]json.RawMessage
+			require.NoError(t, json.Unmarshal(enc, &fields))
+			sig, _ := json.Marshal([]json.RawMessage{fields["v"], fields["r"], fields["s"]})
+			fields["signature"] = sig
+			delete(fields, "v")
+			delete(fields, "r")
+			delete(fields, "s")
+			input, _ := json.Marshal(fields)
+
+			// The strict decoder ignores the array and misses the signature.
+			require.ErrorContains(t, new(Transaction).UnmarshalJSON(input), "missing required field 'v'")
+
+			var have Transaction
+			require.NoError(t, have.UnmarshalJSONLenient(input))
+			require.Equal(t, want.Hash(), have.Hash())
+			from, err := Sender(signer, &have)
+			require.NoError(t, err)
+			require.Equal(t, crypto.PubkeyToAddress(key.PublicKey), from)
+
+			// Both forms at once are rejected.
+			fields["v"] = json.RawMessage(`"0x0"`)
+			input, _ = json.Marshal(fields)
+			require.EqualError(t, new(Transaction).UnmarshalJSONLenient(input), "conflicting 'signature' and 'v' in transaction")
+		})
+	}
+
+	const legacyJSON = `{"type":"0x0","nonce":"0x0","to":null,"gas":"0x5208","gasPrice":"0x1","value":"0x0","input":"0x","signature":%s}`
+	require.ErrorContains(t, new(Transaction).UnmarshalJSONLenient([]byte(fmt.Sprintf(legacyJSON, `["0x1b","0x1"]`))), "have 2 values, want 3")
+	require.ErrorContains(t, new(Transaction).UnmarshalJSONLenient([]byte(fmt.Sprintf(legacyJSON, `"0x1b"`))), "invalid field 'signature'")
+}
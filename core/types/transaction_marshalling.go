@@ -17,25 +17,81 @@
 package types
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
+	"math"
 	"math/big"
+	"runtime"
+	"sync"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto/kzg4844"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/holiman/uint256"
 )
 
-// txJSON is the JSON representation of transactions.
-type txJSON struct {
+// MaxTxInputBytes is the maximum calldata size accepted when decoding a
+// transaction from JSON. Zero, the default, disables the check. Canonical
+// blocks can contain transactions with megabytes of calldata, so a limit should
+// only be set by programs which decode untrusted submissions, not blocks.
+var MaxTxInputBytes = 0
+
+// ErrTxInputTooLarge is returned when decoding a transaction whose calldata
+// exceeds MaxTxInputBytes.
+var ErrTxInputTooLarge = errors.New("transaction input too large")
+
+// MaxAccessListEntries and MaxStorageKeysPerEntry limit the size of access lists
+// accepted when decoding a transaction from JSON. The defaults are above what
+// fits into the gas limit of a 30M gas block. Zero disables the respective check.
+var (
+	MaxAccessListEntries   = 16384
+	MaxStorageKeysPerEntry = 16384
+)
+
+// ErrAccessListTooLarge is returned when decoding a transaction whose access
+// list exceeds MaxAccessListEntries or MaxStorageKeysPerEntry.
+var ErrAccessListTooLarge = errors.New("transaction access list too large")
+
+// AllowZeroGasDeposits disables the rejection of deposit transactions with a
+// zero gas limit by UnmarshalJSONUntrusted. Such deposits are valid
+// protocol-wise, so they are always accepted by UnmarshalJSON, but submissions
+// of them almost always indicate a bug in the producer.
+var AllowZeroGasDeposits = false
+
+// MaxLegacyChainID is the maximum chain ID accepted in the EIP-155 signature of
+// a legacy transaction when decoding from JSON. The default is the limit of
+// EIP-2294, which keeps the v value within 64 bits. Zero disables the check.
+var MaxLegacyChainID uint64 = math.MaxUint64/2 - 36
+
+// EnforceMaxInitCodeSize enables the EIP-3860 limit of params.MaxInitCodeSize
+// on the init code of contract creations when decoding a transaction from JSON.
+// The limit only applies from Shanghai onwards, so the check is disabled by
+// default.
+var EnforceMaxInitCodeSize = false
+
+// ErrTxInitCodeTooLarge is returned when decoding a contract creation whose init
+// code exceeds params.MaxInitCodeSize while EnforceMaxInitCodeSize is set.
+var ErrTxInitCodeTooLarge = errors.New("transaction init code too large")
+
+// TxJSON is the JSON representation of transactions. It is exported for the
+// JSON codecs of transaction types registered with RegisterTxType.
+//
+// Encoded objects always list their keys in the order of the struct fields
+// below, for every transaction type. Some consumers hash the encoded bytes, so
+// fields must not be reordered, and new fields must only be appended to the
+// end of their group. The golden files in testdata/txjson pin the output.
+type TxJSON struct {
 	Type hexutil.Uint64 `json:"type"`
 
 	ChainID              *hexutil.Big    `json:"chainId,omitempty"`
 	Nonce                *hexutil.Uint64 `json:"nonce"`
-	To                   *common.Address `json:"to"`
+	To                   *common.Address `json:"to"` // always emitted, null means contract creation
 	Gas                  *hexutil.Uint64 `json:"gas"`
 	GasPrice             *hexutil.Big    `json:"gasPrice"`
 	MaxPriorityFeePerGas *hexutil.Big    `json:"maxPriorityFeePerGas"`
@@ -45,6 +101,9 @@ type txJSON struct {
 	Input                *hexutil.Bytes  `json:"input"`
 	AccessList           *AccessList     `json:"accessList,omitempty"`
 	BlobVersionedHashes  []common.Hash   `json:"blobVersionedHashes,omitempty"`
+	Blobs                []hexutil.Bytes `json:"blobs,omitempty"`
+	Commitments          []hexutil.Bytes `json:"commitments,omitempty"`
+	Proofs               []hexutil.Bytes `json:"proofs,omitempty"`
 	V                    *hexutil.Big    `json:"v"`
 	R                    *hexutil.Big    `json:"r"`
 	S                    *hexutil.Big    `json:"s"`
@@ -61,368 +120,965 @@ type txJSON struct {
 
 // MarshalJSON marshals as JSON with a hash.
 func (tx *Transaction) MarshalJSON() ([]byte, error) {
-	var enc txJSON
+	return json.Marshal(tx.encodeJSON())
+}
+
+// MarshalTxJSONWithBaseFee marshals the transaction as JSON like MarshalJSON,
+// additionally including the 'effectiveGasPrice' paid by the transaction when
+// included in a block with the given base fee. The result can be decoded with
+// UnmarshalJSON, which ignores the extra field.
+func MarshalTxJSONWithBaseFee(tx *Transaction, baseFee *big.Int) ([]byte, error) {
+	return json.Marshal(&struct {
+		*TxJSON
+		EffectiveGasPrice *hexutil.Big `json:"effectiveGasPrice"`
+	}{
+		TxJSON:            tx.encodeJSON(),
+		EffectiveGasPrice: (*hexutil.Big)(tx.inner.effectiveGasPrice(new(big.Int), baseFee)),
+	})
+}
+
+// MarshalTxJSONWithSender marshals the transaction as JSON like MarshalJSON,
+// additionally including the sender in the 'from' field. Deposit transactions
+// carry their sender, for all other transactions it is recovered with the given
+// signer, which may be nil for deposits.
+func MarshalTxJSONWithSender(tx *Transaction, signer Signer) ([]byte, error) {
+	enc := tx.encodeJSON()
+	if enc.From == nil {
+		if signer == nil {
+			return nil, errors.New("signer required to derive transaction sender")
+		}
+		from, err := Sender(signer, tx)
+		if err != nil {
+			return nil, err
+		}
+		enc.From = &from
+	}
+	return json.Marshal(enc)
+}
+
+// MarshalTxJSONWithCachedSender marshals the transaction as JSON like
+// MarshalJSON, additionally including the 'from' field if the sender is known
+// without signature recovery. This is the case for deposits, and for other
+// transactions whose sender has been derived with Sender before.
+func MarshalTxJSONWithCachedSender(tx *Transaction) ([]byte, error) {
+	enc := tx.encodeJSON()
+	if enc.From == nil {
+		if sc := tx.from.Load(); sc != nil && sc.(sigCache).signer != nil {
+			from := sc.(sigCache).from
+			enc.From = &from
+		}
+	}
+	return json.Marshal(enc)
+}
+
+// MarshalTxJSONChecksummed marshals the transaction as JSON like MarshalJSON, but
+// emits the 'to' and 'from' addresses in EIP-55 mixed-case checksum form. As the
+// fields replace those of the standard encoding, they are placed last in the
+// object. The result can be decoded with UnmarshalJSON.
+func MarshalTxJSONChecksummed(tx *Transaction) ([]byte, error) {
+	enc := tx.encodeJSON()
+	return json.Marshal(&struct {
+		*TxJSON
+		To   *string `json:"to"`
+		From *string `json:"from,omitempty"`
+	}{
+		TxJSON: enc,
+		To:     checksumAddress(enc.To),
+		From:   checksumAddress(enc.From),
+	})
+}
+
+// MarshalTxJSONDecimalType marshals the transaction as JSON like MarshalJSON, but
+// emits the 'type' field as a decimal JSON number instead of a hex string, for
+// consumers which cannot parse the latter. As the field replaces the one of the
+// standard encoding, it is placed last in the object.
+func MarshalTxJSONDecimalType(tx *Transaction) ([]byte, error) {
+	enc := tx.encodeJSON()
+	return json.Marshal(&struct {
+		*TxJSON
+		Type uint64 `json:"type"`
+	}{
+		TxJSON: enc,
+		Type:   uint64(enc.Type),
+	})
+}
+
+func checksumAddress(a *common.Address) *string {
+	if a == nil {
+		return nil
+	}
+	hex := a.Hex()
+	return &hex
+}
+
+// txSummaryJSON is the abbreviated JSON representation of transactions.
+type txSummaryJSON struct {
+	Type  hexutil.Uint64  `json:"type"`
+	Hash  common.Hash     `json:"hash"`
+	From  *common.Address `json:"from,omitempty"`
+	To    *common.Address `json:"to"`
+	Gas   hexutil.Uint64  `json:"gas"`
+	Value *hexutil.Big    `json:"value"`
+}
+
+// SummaryJSON marshals an abbreviated JSON representation of the transaction,
+// suitable for log lines. It omits calldata and signature values. The sender is
+// only included for deposit transactions, which carry it explicitly.
+func (tx *Transaction) SummaryJSON() ([]byte, error) {
+	enc := txSummaryJSON{
+		Type:  hexutil.Uint64(tx.Type()),
+		Hash:  tx.Hash(),
+		To:    tx.To(),
+		Gas:   hexutil.Uint64(tx.Gas()),
+		Value: (*hexutil.Big)(tx.Value()),
+	}
+	if dep, ok := tx.depositTx(); ok {
+		enc.From = &dep.From
+	}
+	return json.Marshal(&enc)
+}
+
+// encodeJSON returns the JSON representation of the transaction.
+func (tx *Transaction) encodeJSON() *TxJSON {
+	var enc TxJSON
 	// These are set for all tx types.
 	enc.Hash = tx.Hash()
 	enc.Type = hexutil.Uint64(tx.Type())
 
 	// Other fields are set conditionally depending on tx type.
-	switch itx := tx.inner.(type) {
-	case *LegacyTx:
-		enc.Nonce = (*hexutil.Uint64)(&itx.Nonce)
-		enc.To = tx.To()
-		enc.Gas = (*hexutil.Uint64)(&itx.Gas)
-		enc.GasPrice = (*hexutil.Big)(itx.GasPrice)
-		enc.Value = (*hexutil.Big)(itx.Value)
-		enc.Input = (*hexutil.Bytes)(&itx.Data)
-		enc.V = (*hexutil.Big)(itx.V)
-		enc.R = (*hexutil.Big)(itx.R)
-		enc.S = (*hexutil.Big)(itx.S)
-
-	case *AccessListTx:
-		enc.ChainID = (*hexutil.Big)(itx.ChainID)
-		enc.Nonce = (*hexutil.Uint64)(&itx.Nonce)
-		enc.To = tx.To()
-		enc.Gas = (*hexutil.Uint64)(&itx.Gas)
-		enc.GasPrice = (*hexutil.Big)(itx.GasPrice)
-		enc.Value = (*hexutil.Big)(itx.Value)
-		enc.Input = (*hexutil.Bytes)(&itx.Data)
-		enc.AccessList = &itx.AccessList
-		enc.V = (*hexutil.Big)(itx.V)
-		enc.R = (*hexutil.Big)(itx.R)
-		enc.S = (*hexutil.Big)(itx.S)
-
-	case *DynamicFeeTx:
-		enc.ChainID = (*hexutil.Big)(itx.ChainID)
-		enc.Nonce = (*hexutil.Uint64)(&itx.Nonce)
-		enc.To = tx.To()
-		enc.Gas = (*hexutil.Uint64)(&itx.Gas)
-		enc.MaxFeePerGas = (*hexutil.Big)(itx.GasFeeCap)
-		enc.MaxPriorityFeePerGas = (*hexutil.Big)(itx.GasTipCap)
-		enc.Value = (*hexutil.Big)(itx.Value)
-		enc.Input = (*hexutil.Bytes)(&itx.Data)
-		enc.AccessList = &itx.AccessList
-		enc.V = (*hexutil.Big)(itx.V)
-		enc.R = (*hexutil.Big)(itx.R)
-		enc.S = (*hexutil.Big)(itx.S)
-
-	case *BlobTx:
-		enc.ChainID = (*hexutil.Big)(itx.ChainID.ToBig())
-		enc.Nonce = (*hexutil.Uint64)(&itx.Nonce)
-		enc.Gas = (*hexutil.Uint64)(&itx.Gas)
-		enc.MaxFeePerGas = (*hexutil.Big)(itx.GasFeeCap.ToBig())
-		enc.MaxPriorityFeePerGas = (*hexutil.Big)(itx.GasTipCap.ToBig())
-		enc.MaxFeePerDataGas = (*hexutil.Big)(itx.BlobFeeCap.ToBig())
-		enc.Value = (*hexutil.Big)(itx.Value.ToBig())
-		enc.Input = (*hexutil.Bytes)(&itx.Data)
-		enc.AccessList = &itx.AccessList
-		enc.BlobVersionedHashes = itx.BlobHashes
-		enc.To = tx.To()
-		enc.V = (*hexutil.Big)(itx.V.ToBig())
-		enc.R = (*hexutil.Big)(itx.R.ToBig())
-		enc.S = (*hexutil.Big)(itx.S.ToBig())
+	if codec, ok := txTypeCodecs[tx.Type()]; ok {
+		codec.marshal(tx.inner, &enc)
+	}
+	return &enc
+}
 
-	case *DepositTx:
-		enc.Gas = (*hexutil.Uint64)(&itx.Gas)
-		enc.Value = (*hexutil.Big)(itx.Value)
-		enc.Input = (*hexutil.Bytes)(&itx.Data)
-		enc.To = tx.To()
-		enc.SourceHash = &itx.SourceHash
-		enc.From = &itx.From
-		if itx.Mint != nil {
-			enc.Mint = (*hexutil.Big)(itx.Mint)
+// marshalLegacyTxJSON sets the JSON fields of a LegacyTx.
+func marshalLegacyTxJSON(inner TxData, enc *TxJSON) {
+	itx := inner.(*LegacyTx)
+	enc.Nonce = (*hexutil.Uint64)(&itx.Nonce)
+	enc.To = copyAddressPtr(itx.To)
+	enc.Gas = (*hexutil.Uint64)(&itx.Gas)
+	enc.GasPrice = (*hexutil.Big)(itx.GasPrice)
+	enc.Value = (*hexutil.Big)(itx.Value)
+	enc.Input = (*hexutil.Bytes)(&itx.Data)
+	enc.V = (*hexutil.Big)(itx.V)
+	enc.R = (*hexutil.Big)(itx.R)
+	enc.S = (*hexutil.Big)(itx.S)
+}
+
+// marshalAccessListTxJSON sets the JSON fields of an AccessListTx.
+func marshalAccessListTxJSON(inner TxData, enc *TxJSON) {
+	itx := inner.(*AccessListTx)
+	enc.ChainID = (*hexutil.Big)(itx.ChainID)
+	enc.Nonce = (*hexutil.Uint64)(&itx.Nonce)
+	enc.To = copyAddressPtr(itx.To)
+	enc.Gas = (*hexutil.Uint64)(&itx.Gas)
+	enc.GasPrice = (*hexutil.Big)(itx.GasPrice)
+	enc.Value = (*hexutil.Big)(itx.Value)
+	enc.Input = (*hexutil.Bytes)(&itx.Data)
+	enc.AccessList = &itx.AccessList
+	enc.V = (*hexutil.Big)(itx.V)
+	enc.R = (*hexutil.Big)(itx.R)
+	enc.S = (*hexutil.Big)(itx.S)
+}
+
+// marshalDynamicFeeTxJSON sets the JSON fields of a DynamicFeeTx.
+func marshalDynamicFeeTxJSON(inner TxData, enc *TxJSON) {
+	itx := inner.(*DynamicFeeTx)
+	enc.ChainID = (*hexutil.Big)(itx.ChainID)
+	enc.Nonce = (*hexutil.Uint64)(&itx.Nonce)
+	enc.To = copyAddressPtr(itx.To)
+	enc.Gas = (*hexutil.Uint64)(&itx.Gas)
+	enc.MaxFeePerGas = (*hexutil.Big)(itx.GasFeeCap)
+	enc.MaxPriorityFeePerGas = (*hexutil.Big)(itx.GasTipCap)
+	enc.Value = (*hexutil.Big)(itx.Value)
+	enc.Input = (*hexutil.Bytes)(&itx.Data)
+	enc.AccessList = &itx.AccessList
+	enc.V = (*hexutil.Big)(itx.V)
+	enc.R = (*hexutil.Big)(itx.R)
+	enc.S = (*hexutil.Big)(itx.S)
+}
+
+// marshalBlobTxJSON sets the JSON fields of a BlobTx.
+func marshalBlobTxJSON(inner TxData, enc *TxJSON) {
+	itx := inner.(*BlobTx)
+	enc.ChainID = (*hexutil.Big)(itx.ChainID.ToBig())
+	enc.Nonce = (*hexutil.Uint64)(&itx.Nonce)
+	enc.Gas = (*hexutil.Uint64)(&itx.Gas)
+	enc.MaxFeePerGas = (*hexutil.Big)(itx.GasFeeCap.ToBig())
+	enc.MaxPriorityFeePerGas = (*hexutil.Big)(itx.GasTipCap.ToBig())
+	enc.MaxFeePerDataGas = (*hexutil.Big)(itx.BlobFeeCap.ToBig())
+	enc.Value = (*hexutil.Big)(itx.Value.ToBig())
+	enc.Input = (*hexutil.Bytes)(&itx.Data)
+	enc.AccessList = &itx.AccessList
+	enc.BlobVersionedHashes = itx.BlobHashes
+	if sc := itx.Sidecar; sc != nil {
+		enc.Blobs = make([]hexutil.Bytes, len(sc.Blobs))
+		for i := range sc.Blobs {
+			enc.Blobs[i] = sc.Blobs[i][:]
+		}
+		enc.Commitments = make([]hexutil.Bytes, len(sc.Commitments))
+		for i := range sc.Commitments {
+			enc.Commitments[i] = sc.Commitments[i][:]
+		}
+		enc.Proofs = make([]hexutil.Bytes, len(sc.Proofs))
+		for i := range sc.Proofs {
+			enc.Proofs[i] = sc.Proofs[i][:]
 		}
-		enc.IsSystemTx = &itx.IsSystemTransaction
-		// other fields will show up as null.
 	}
-	return json.Marshal(&enc)
+	enc.To = copyAddressPtr(itx.To)
+	enc.V = (*hexutil.Big)(itx.V.ToBig())
+	enc.R = (*hexutil.Big)(itx.R.ToBig())
+	enc.S = (*hexutil.Big)(itx.S.ToBig())
+}
+
+// marshalDepositTxJSON sets the JSON fields of a DepositTx. Deposits decoded
+// with an effective nonce, e.g. from RPC responses, include it as 'nonce' so
+// that it survives a JSON round trip. For other deposits, 'nonce' is null.
+func marshalDepositTxJSON(inner TxData, enc *TxJSON) {
+	var itx *DepositTx
+	switch inner := inner.(type) {
+	case *DepositTx:
+		itx = inner
+	case *depositTxWithNonce:
+		itx = &inner.DepositTx
+		enc.Nonce = (*hexutil.Uint64)(&inner.EffectiveNonce)
+	}
+	enc.Gas = (*hexutil.Uint64)(&itx.Gas)
+	enc.Value = (*hexutil.Big)(itx.Value)
+	enc.Input = (*hexutil.Bytes)(&itx.Data)
+	enc.To = copyAddressPtr(itx.To)
+	enc.SourceHash = &itx.SourceHash
+	enc.From = &itx.From
+	if itx.Mint != nil {
+		enc.Mint = (*hexutil.Big)(itx.Mint)
+	}
+	enc.IsSystemTx = &itx.IsSystemTransaction
+	// other fields will show up as null.
 }
 
 // UnmarshalJSON unmarshals from JSON.
 func (tx *Transaction) UnmarshalJSON(input []byte) error {
-	var dec txJSON
-	if err := json.Unmarshal(input, &dec); err != nil {
+	var dec TxJSON
+	if err := unmarshalTxJSON(input, &dec); err != nil {
 		return err
 	}
+	return tx.decodeJSON(&dec)
+}
 
-	// Decode / verify fields according to transaction type.
-	var inner TxData
-	switch dec.Type {
-	case LegacyTxType:
-		var itx LegacyTx
-		inner = &itx
-		if dec.Nonce == nil {
-			return errors.New("missing required field 'nonce' in transaction")
-		}
-		itx.Nonce = uint64(*dec.Nonce)
-		if dec.To != nil {
-			itx.To = dec.To
-		}
-		if dec.Gas == nil {
-			return errors.New("missing required field 'gas' in transaction")
-		}
-		itx.Gas = uint64(*dec.Gas)
-		if dec.GasPrice == nil {
-			return errors.New("missing required field 'gasPrice' in transaction")
-		}
-		itx.GasPrice = (*big.Int)(dec.GasPrice)
-		if dec.Value == nil {
-			return errors.New("missing required field 'value' in transaction")
-		}
-		itx.Value = (*big.Int)(dec.Value)
-		if dec.Input == nil {
-			return errors.New("missing required field 'input' in transaction")
-		}
-		itx.Data = *dec.Input
-		if dec.V == nil {
-			return errors.New("missing required field 'v' in transaction")
-		}
-		itx.V = (*big.Int)(dec.V)
-		if dec.R == nil {
-			return errors.New("missing required field 'r' in transaction")
-		}
-		itx.R = (*big.Int)(dec.R)
-		if dec.S == nil {
-			return errors.New("missing required field 's' in transaction")
-		}
-		itx.S = (*big.Int)(dec.S)
-		withSignature := itx.V.Sign() != 0 || itx.R.Sign() != 0 || itx.S.Sign() != 0
-		if withSignature {
-			if err := sanityCheckSignature(itx.V, itx.R, itx.S, true); err != nil {
-				return err
-			}
-		}
+// DecodeTransactionFromReader decodes a JSON-encoded transaction from r like
+// UnmarshalJSON, without first reading the complete input into memory. Only the
+// first JSON value is read from r.
+func DecodeTransactionFromReader(r io.Reader) (*Transaction, error) {
+	var dec *TxJSON
+	if err := json.NewDecoder(r).Decode(&dec); err != nil {
+		return nil, err
+	}
+	if dec == nil {
+		return nil, errTxNull
+	}
+	return DecodeTxJSON(dec)
+}
 
-	case AccessListTxType:
-		var itx AccessListTx
-		inner = &itx
-		if dec.ChainID == nil {
-			return errors.New("missing required field 'chainId' in transaction")
-		}
-		itx.ChainID = (*big.Int)(dec.ChainID)
-		if dec.Nonce == nil {
-			return errors.New("missing required field 'nonce' in transaction")
-		}
-		itx.Nonce = uint64(*dec.Nonce)
-		if dec.To != nil {
-			itx.To = dec.To
-		}
-		if dec.Gas == nil {
-			return errors.New("missing required field 'gas' in transaction")
-		}
-		itx.Gas = uint64(*dec.Gas)
-		if dec.GasPrice == nil {
-			return errors.New("missing required field 'gasPrice' in transaction")
-		}
-		itx.GasPrice = (*big.Int)(dec.GasPrice)
-		if dec.Value == nil {
-			return errors.New("missing required field 'value' in transaction")
-		}
-		itx.Value = (*big.Int)(dec.Value)
-		if dec.Input == nil {
-			return errors.New("missing required field 'input' in transaction")
-		}
-		itx.Data = *dec.Input
-		if dec.V == nil {
-			return errors.New("missing required field 'v' in transaction")
-		}
-		if dec.AccessList != nil {
-			itx.AccessList = *dec.AccessList
-		}
-		itx.V = (*big.Int)(dec.V)
-		if dec.R == nil {
-			return errors.New("missing required field 'r' in transaction")
-		}
-		itx.R = (*big.Int)(dec.R)
-		if dec.S == nil {
-			return errors.New("missing required field 's' in transaction")
+// EncodeTxJSON returns the JSON representation of the transaction, as encoded by
+// MarshalJSON. It allows other encodings to be built on the same fields. The
+// result shares memory with the transaction and must not be modified.
+func (tx *Transaction) EncodeTxJSON() *TxJSON {
+	return tx.encodeJSON()
+}
+
+// DecodeTxJSON creates a transaction from its JSON representation, applying the
+// same checks as UnmarshalJSON.
+func DecodeTxJSON(dec *TxJSON) (*Transaction, error) {
+	tx := new(Transaction)
+	if err := tx.decodeJSON(dec); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+var errTxNull = errors.New("transaction is null")
+
+// unmarshalTxJSON decodes the JSON representation of a transaction. Unlike
+// json.Unmarshal, it rejects a literal null, which would otherwise decode as an
+// empty legacy transaction and fail with a confusing missing field error.
+func unmarshalTxJSON(input []byte, dec *TxJSON) error {
+	if bytes.Equal(bytes.TrimSpace(input), []byte("null")) {
+		return errTxNull
+	}
+	return json.Unmarshal(input, dec)
+}
+
+// UnmarshalJSONWithChainID decodes a transaction from JSON like UnmarshalJSON,
+// and additionally verifies that the 'chainId' field, if present, matches the
+// given chain ID. This applies to all transaction types. Deposit transactions
+// are not bound to a chain, but some tooling includes a chain ID regardless, so
+// for them zero is accepted as well. The chain ID must not be nil.
+func (tx *Transaction) UnmarshalJSONWithChainID(input []byte, chainID *big.Int) error {
+	if chainID == nil {
+		return errors.New("missing chain id to verify transaction against")
+	}
+	var dec TxJSON
+	if err := unmarshalTxJSON(input, &dec); err != nil {
+		return err
+	}
+	if dec.ChainID != nil {
+		have := dec.ChainID.ToInt()
+		if have.Cmp(chainID) != 0 && !(dec.Type == DepositTxType && have.Sign() == 0) {
+			return fmt.Errorf("invalid chain id %d in transaction, want %d", have, chainID)
 		}
-		itx.S = (*big.Int)(dec.S)
-		withSignature := itx.V.Sign() != 0 || itx.R.Sign() != 0 || itx.S.Sign() != 0
-		if withSignature {
-			if err := sanityCheckSignature(itx.V, itx.R, itx.S, false); err != nil {
-				return err
+	}
+	return tx.decodeJSON(&dec)
+}
+
+// UnmarshalTransactionsParallel decodes a batch of JSON-encoded transactions
+// using the given number of goroutines, or one per CPU if workers is not
+// positive. The results are in input order. If any input fails to decode, the
+// error of the one with the lowest index is returned.
+func UnmarshalTransactionsParallel(inputs [][]byte, workers int) ([]*Transaction, error) {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	var (
+		txs  = make([]*Transaction, len(inputs))
+		errs = make([]error, len(inputs))
+		next = make(chan int)
+		wg   sync.WaitGroup
+	)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				tx := new(Transaction)
+				if errs[i] = tx.UnmarshalJSON(inputs[i]); errs[i] == nil {
+					txs[i] = tx
+				}
 			}
-		}
+		}()
+	}
+	for i := range inputs {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
 
-	case DynamicFeeTxType:
-		var itx DynamicFeeTx
-		inner = &itx
-		if dec.ChainID == nil {
-			return errors.New("missing required field 'chainId' in transaction")
-		}
-		itx.ChainID = (*big.Int)(dec.ChainID)
-		if dec.Nonce == nil {
-			return errors.New("missing required field 'nonce' in transaction")
-		}
-		itx.Nonce = uint64(*dec.Nonce)
-		if dec.To != nil {
-			itx.To = dec.To
+	for i, err := range errs {
+		if err != nil {
+			return nil, fmt.Errorf("transaction %d: %w", i, err)
 		}
-		if dec.Gas == nil {
-			return errors.New("missing required field 'gas' for txdata")
-		}
-		itx.Gas = uint64(*dec.Gas)
-		if dec.MaxPriorityFeePerGas == nil {
-			return errors.New("missing required field 'maxPriorityFeePerGas' for txdata")
+	}
+	return txs, nil
+}
+
+// UnmarshalRawTxObject decodes a JSON object of the form {"raw": "0x..."}, which
+// wraps the canonical binary encoding of a transaction instead of listing its
+// fields. The object must not contain any other fields.
+func UnmarshalRawTxObject(input []byte) (*Transaction, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(input, &fields); err != nil {
+		return nil, err
+	}
+	raw, ok := fields["raw"]
+	if !ok {
+		return nil, errors.New("missing required field 'raw' in transaction object")
+	}
+	if len(fields) > 1 {
+		return nil, errors.New("unexpected structured fields next to 'raw' in transaction object")
+	}
+	var enc hexutil.Bytes
+	if err := json.Unmarshal(raw, &enc); err != nil {
+		return nil, fmt.Errorf("invalid field 'raw' in transaction object: %w", err)
+	}
+	tx := new(Transaction)
+	if err := tx.UnmarshalBinary(enc); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+// DecodeTransactionAuto decodes a transaction given in any of the formats used
+// by transaction APIs: a JSON object as accepted by UnmarshalJSON, a 0x-prefixed
+// hex string of the canonical binary encoding, or the binary encoding itself.
+// The format is inferred from the first non-whitespace characters, which is
+// unambiguous as no transaction type byte is '{' or '0'.
+func DecodeTransactionAuto(data []byte) (*Transaction, error) {
+	trimmed := bytes.TrimSpace(data)
+	tx := new(Transaction)
+	switch {
+	case len(trimmed) == 0:
+		return nil, errors.New("empty transaction input")
+	case trimmed[0] == '{':
+		if err := tx.UnmarshalJSON(trimmed); err != nil {
+			return nil, err
 		}
-		itx.GasTipCap = (*big.Int)(dec.MaxPriorityFeePerGas)
-		if dec.MaxFeePerGas == nil {
-			return errors.New("missing required field 'maxFeePerGas' for txdata")
+	case bytes.HasPrefix(trimmed, []byte("0x")):
+		enc, err := hexutil.Decode(string(trimmed))
+		if err != nil {
+			return nil, fmt.Errorf("invalid hex encoded transaction: %w", err)
 		}
-		itx.GasFeeCap = (*big.Int)(dec.MaxFeePerGas)
-		if dec.Value == nil {
-			return errors.New("missing required field 'value' in transaction")
+		if err := tx.UnmarshalBinary(enc); err != nil {
+			return nil, err
 		}
-		itx.Value = (*big.Int)(dec.Value)
-		if dec.Input == nil {
-			return errors.New("missing required field 'input' in transaction")
+	default:
+		// Whitespace is only insignificant in the text formats.
+		if err := tx.UnmarshalBinary(data); err != nil {
+			return nil, err
 		}
-		itx.Data = *dec.Input
-		if dec.V == nil {
-			return errors.New("missing required field 'v' in transaction")
+	}
+	return tx, nil
+}
+
+// UnmarshalJSONPreserving decodes a transaction from JSON like UnmarshalJSON, and
+// also returns a copy of the input with insignificant whitespace removed. Unlike
+// re-encoding the transaction, this preserves the key order, null values and
+// unknown fields of the original input, e.g. for audit logs.
+func UnmarshalJSONPreserving(input []byte) (*Transaction, []byte, error) {
+	tx := new(Transaction)
+	if err := tx.UnmarshalJSON(input); err != nil {
+		return nil, nil, err
+	}
+	var raw bytes.Buffer
+	if err := json.Compact(&raw, input); err != nil {
+		return nil, nil, err
+	}
+	return tx, raw.Bytes(), nil
+}
+
+// UnmarshalJSONUntrusted decodes a transaction received from an untrusted source
+// like UnmarshalJSON, but ignores the 'isSystemTx' field of deposit
+// transactions. System transactions are exempt from the block gas limit, so
+// only internally derived deposits may carry the flag. Deposits from the zero
+// address are rejected with ErrDepositZeroFrom, as no derived deposit has that
+// sender, and deposits with a zero gas limit with ErrDepositZeroGas, unless
+// AllowZeroGasDeposits is set.
+func (tx *Transaction) UnmarshalJSONUntrusted(input []byte) error {
+	var dec TxJSON
+	if err := unmarshalTxJSON(input, &dec); err != nil {
+		return err
+	}
+	if dec.Type == DepositTxType {
+		dec.IsSystemTx = nil
+		if dec.From != nil && *dec.From == (common.Address{}) {
+			return ErrDepositZeroFrom
 		}
-		if dec.AccessList != nil {
-			itx.AccessList = *dec.AccessList
+		if dec.Gas != nil && *dec.Gas == 0 && !AllowZeroGasDeposits {
+			return ErrDepositZeroGas
 		}
-		itx.V = (*big.Int)(dec.V)
-		if dec.R == nil {
-			return errors.New("missing required field 'r' in transaction")
+	}
+	return tx.decodeJSON(&dec)
+}
+
+// UnmarshalCallObject decodes an unsigned, transaction-shaped call object as
+// used by eth_call and eth_estimateGas. Unlike UnmarshalJSON, it accepts objects
+// without signature values, nonce, gas, value or input, which default to zero.
+// All fields which are present are verified as in UnmarshalJSON.
+func UnmarshalCallObject(input []byte) (*Transaction, error) {
+	var dec TxJSON
+	if err := unmarshalTxJSON(input, &dec); err != nil {
+		return nil, err
+	}
+	if dec.Nonce == nil {
+		dec.Nonce = new(hexutil.Uint64)
+	}
+	if dec.Gas == nil {
+		dec.Gas = new(hexutil.Uint64)
+	}
+	if dec.Value == nil {
+		dec.Value = new(hexutil.Big)
+	}
+	if dec.Input == nil {
+		dec.Input = new(hexutil.Bytes)
+	}
+	if dec.V == nil {
+		dec.V = new(hexutil.Big)
+	}
+	if dec.R == nil {
+		dec.R = new(hexutil.Big)
+	}
+	if dec.S == nil {
+		dec.S = new(hexutil.Big)
+	}
+	tx := new(Transaction)
+	if err := tx.decodeJSON(&dec); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+// decodeJSON verifies the fields of the JSON representation according to the
+// transaction type and sets the inner transaction.
+func (tx *Transaction) decodeJSON(dec *TxJSON) error {
+	if dec.Input != nil && MaxTxInputBytes > 0 && len(*dec.Input) > MaxTxInputBytes {
+		return fmt.Errorf("%w: have %d bytes, max %d", ErrTxInputTooLarge, len(*dec.Input), MaxTxInputBytes)
+	}
+
+	// Decode / verify fields according to transaction type.
+	codec, ok := txTypeCodecs[byte(dec.Type)]
+	if dec.Type > 0xff || !ok {
+		return ErrTxTypeNotSupported
+	}
+	inner, err := codec.unmarshal(dec)
+	if err != nil {
+		return err
+	}
+	if EnforceMaxInitCodeSize && inner.to() == nil && len(inner.data()) > params.MaxInitCodeSize {
+		return fmt.Errorf("%w: have %d bytes, max %d", ErrTxInitCodeTooLarge, len(inner.data()), params.MaxInitCodeSize)
+	}
+
+	// Now set the inner transaction.
+	if err := tx.commitDecoded(inner, 0); err != nil {
+		return err
+	}
+
+	// TODO: check hash here?
+	return nil
+}
+
+// unmarshalLegacyTxJSON verifies the JSON fields of a LegacyTx and decodes them.
+func unmarshalLegacyTxJSON(dec *TxJSON) (TxData, error) {
+	var itx LegacyTx
+	if dec.BlobVersionedHashes != nil {
+		return nil, errors.New("unexpected field 'blobVersionedHashes' in transaction")
+	}
+	if dec.Nonce == nil {
+		return nil, errors.New("missing required field 'nonce' in transaction")
+	}
+	itx.Nonce = uint64(*dec.Nonce)
+	if dec.To != nil {
+		itx.To = dec.To
+	}
+	if dec.Gas == nil {
+		return nil, errors.New("missing required field 'gas' in transaction")
+	}
+	itx.Gas = uint64(*dec.Gas)
+	if dec.GasPrice == nil {
+		return nil, errors.New("missing required field 'gasPrice' in transaction")
+	}
+	itx.GasPrice = (*big.Int)(dec.GasPrice)
+	if dec.Value == nil {
+		return nil, errors.New("missing required field 'value' in transaction")
+	}
+	itx.Value = (*big.Int)(dec.Value)
+	if dec.Input == nil {
+		return nil, errors.New("missing required field 'input' in transaction")
+	}
+	itx.Data = common.CopyBytes(*dec.Input)
+	if dec.V == nil {
+		return nil, errors.New("missing required field 'v' in transaction")
+	}
+	itx.V = (*big.Int)(dec.V)
+	if dec.R == nil {
+		return nil, errors.New("missing required field 'r' in transaction")
+	}
+	itx.R = (*big.Int)(dec.R)
+	if dec.S == nil {
+		return nil, errors.New("missing required field 's' in transaction")
+	}
+	itx.S = (*big.Int)(dec.S)
+	withSignature := itx.V.Sign() != 0 || itx.R.Sign() != 0 || itx.S.Sign() != 0
+	if withSignature {
+		if isProtectedV(itx.V) && MaxLegacyChainID > 0 {
+			if id := deriveChainId(itx.V); !id.IsUint64() || id.Uint64() > MaxLegacyChainID {
+				return nil, fmt.Errorf("invalid chain id %d in legacy transaction signature, max %d", id, MaxLegacyChainID)
+			}
 		}
-		itx.R = (*big.Int)(dec.R)
-		if dec.S == nil {
-			return errors.New("missing required field 's' in transaction")
+		if err := sanityCheckSignature(itx.V, itx.R, itx.S, true); err != nil {
+			return nil, err
 		}
-		itx.S = (*big.Int)(dec.S)
-		withSignature := itx.V.Sign() != 0 || itx.R.Sign() != 0 || itx.S.Sign() != 0
-		if withSignature {
-			if err := sanityCheckSignature(itx.V, itx.R, itx.S, false); err != nil {
-				return err
+		// Some tools include the chain ID of legacy transactions, which must then
+		// match the one in the EIP-155 signature.
+		if dec.ChainID != nil && isProtectedV(itx.V) {
+			if have, want := dec.ChainID.ToInt(), deriveChainId(itx.V); have.Cmp(want) != 0 {
+				return nil, fmt.Errorf("invalid chain id %d in legacy transaction, signature is for chain %d", have, want)
 			}
 		}
+	}
+	return &itx, nil
+}
 
-	case BlobTxType:
-		var itx BlobTx
-		inner = &itx
-		if dec.ChainID == nil {
-			return errors.New("missing required field 'chainId' in transaction")
-		}
-		itx.ChainID = uint256.MustFromBig((*big.Int)(dec.ChainID))
-		if dec.Nonce == nil {
-			return errors.New("missing required field 'nonce' in transaction")
-		}
-		itx.Nonce = uint64(*dec.Nonce)
-		if dec.To != nil {
-			itx.To = dec.To
-		}
-		if dec.Gas == nil {
-			return errors.New("missing required field 'gas' for txdata")
-		}
-		itx.Gas = uint64(*dec.Gas)
-		if dec.MaxPriorityFeePerGas == nil {
-			return errors.New("missing required field 'maxPriorityFeePerGas' for txdata")
-		}
-		itx.GasTipCap = uint256.MustFromBig((*big.Int)(dec.MaxPriorityFeePerGas))
-		if dec.MaxFeePerGas == nil {
-			return errors.New("missing required field 'maxFeePerGas' for txdata")
-		}
-		itx.GasFeeCap = uint256.MustFromBig((*big.Int)(dec.MaxFeePerGas))
-		if dec.MaxFeePerDataGas == nil {
-			return errors.New("missing required field 'maxFeePerDataGas' for txdata")
-		}
-		itx.BlobFeeCap = uint256.MustFromBig((*big.Int)(dec.MaxFeePerDataGas))
-		if dec.Value == nil {
-			return errors.New("missing required field 'value' in transaction")
-		}
-		itx.Value = uint256.MustFromBig((*big.Int)(dec.Value))
-		if dec.Input == nil {
-			return errors.New("missing required field 'input' in transaction")
+// unmarshalAccessListTxJSON verifies the JSON fields of an AccessListTx and decodes them.
+func unmarshalAccessListTxJSON(dec *TxJSON) (TxData, error) {
+	var itx AccessListTx
+	if dec.BlobVersionedHashes != nil {
+		return nil, errors.New("unexpected field 'blobVersionedHashes' in transaction")
+	}
+	if dec.ChainID == nil {
+		return nil, errors.New("missing required field 'chainId' in transaction")
+	}
+	itx.ChainID = (*big.Int)(dec.ChainID)
+	if dec.Nonce == nil {
+		return nil, errors.New("missing required field 'nonce' in transaction")
+	}
+	itx.Nonce = uint64(*dec.Nonce)
+	if dec.To != nil {
+		itx.To = dec.To
+	}
+	if dec.Gas == nil {
+		return nil, errors.New("missing required field 'gas' in transaction")
+	}
+	itx.Gas = uint64(*dec.Gas)
+	if dec.GasPrice == nil {
+		return nil, errors.New("missing required field 'gasPrice' in transaction")
+	}
+	itx.GasPrice = (*big.Int)(dec.GasPrice)
+	if dec.Value == nil {
+		return nil, errors.New("missing required field 'value' in transaction")
+	}
+	itx.Value = (*big.Int)(dec.Value)
+	if dec.Input == nil {
+		return nil, errors.New("missing required field 'input' in transaction")
+	}
+	itx.Data = common.CopyBytes(*dec.Input)
+	if dec.V == nil {
+		return nil, errors.New("missing required field 'v' in transaction")
+	}
+	if dec.AccessList != nil {
+		if err := checkAccessListSize(*dec.AccessList); err != nil {
+			return nil, err
 		}
-		itx.Data = *dec.Input
-		if dec.V == nil {
-			return errors.New("missing required field 'v' in transaction")
+		itx.AccessList = fillStorageKeys(*dec.AccessList)
+	}
+	itx.V = (*big.Int)(dec.V)
+	if dec.R == nil {
+		return nil, errors.New("missing required field 'r' in transaction")
+	}
+	itx.R = (*big.Int)(dec.R)
+	if dec.S == nil {
+		return nil, errors.New("missing required field 's' in transaction")
+	}
+	itx.S = (*big.Int)(dec.S)
+	withSignature := itx.V.Sign() != 0 || itx.R.Sign() != 0 || itx.S.Sign() != 0
+	if withSignature {
+		if err := sanityCheckSignature(itx.V, itx.R, itx.S, false); err != nil {
+			return nil, err
 		}
-		if dec.AccessList != nil {
-			itx.AccessList = *dec.AccessList
+	}
+	return &itx, nil
+}
+
+// unmarshalDynamicFeeTxJSON verifies the JSON fields of a DynamicFeeTx and decodes them.
+func unmarshalDynamicFeeTxJSON(dec *TxJSON) (TxData, error) {
+	var itx DynamicFeeTx
+	if dec.BlobVersionedHashes != nil {
+		return nil, errors.New("unexpected field 'blobVersionedHashes' in transaction")
+	}
+	if dec.ChainID == nil {
+		return nil, errors.New("missing required field 'chainId' in transaction")
+	}
+	itx.ChainID = (*big.Int)(dec.ChainID)
+	if dec.Nonce == nil {
+		return nil, errors.New("missing required field 'nonce' in transaction")
+	}
+	itx.Nonce = uint64(*dec.Nonce)
+	if dec.To != nil {
+		itx.To = dec.To
+	}
+	if dec.Gas == nil {
+		return nil, errors.New("missing required field 'gas' for txdata")
+	}
+	itx.Gas = uint64(*dec.Gas)
+	if dec.MaxPriorityFeePerGas == nil {
+		return nil, errors.New("missing required field 'maxPriorityFeePerGas' for txdata")
+	}
+	itx.GasTipCap = (*big.Int)(dec.MaxPriorityFeePerGas)
+	if dec.MaxFeePerGas == nil {
+		return nil, errors.New("missing required field 'maxFeePerGas' for txdata")
+	}
+	itx.GasFeeCap = (*big.Int)(dec.MaxFeePerGas)
+	if dec.Value == nil {
+		return nil, errors.New("missing required field 'value' in transaction")
+	}
+	itx.Value = (*big.Int)(dec.Value)
+	if dec.Input == nil {
+		return nil, errors.New("missing required field 'input' in transaction")
+	}
+	itx.Data = common.CopyBytes(*dec.Input)
+	if dec.V == nil {
+		return nil, errors.New("missing required field 'v' in transaction")
+	}
+	if dec.AccessList != nil {
+		if err := checkAccessListSize(*dec.AccessList); err != nil {
+			return nil, err
 		}
-		if dec.BlobVersionedHashes == nil {
-			return errors.New("missing required field 'blobVersionedHashes' in transaction")
+		itx.AccessList = fillStorageKeys(*dec.AccessList)
+	}
+	itx.V = (*big.Int)(dec.V)
+	if dec.R == nil {
+		return nil, errors.New("missing required field 'r' in transaction")
+	}
+	itx.R = (*big.Int)(dec.R)
+	if dec.S == nil {
+		return nil, errors.New("missing required field 's' in transaction")
+	}
+	itx.S = (*big.Int)(dec.S)
+	withSignature := itx.V.Sign() != 0 || itx.R.Sign() != 0 || itx.S.Sign() != 0
+	if withSignature {
+		if err := sanityCheckSignature(itx.V, itx.R, itx.S, false); err != nil {
+			return nil, err
 		}
-		itx.BlobHashes = dec.BlobVersionedHashes
-		itx.V = uint256.MustFromBig((*big.Int)(dec.V))
-		if dec.R == nil {
-			return errors.New("missing required field 'r' in transaction")
+	}
+	return &itx, nil
+}
+
+// unmarshalBlobTxJSON verifies the JSON fields of a BlobTx and decodes them.
+func unmarshalBlobTxJSON(dec *TxJSON) (TxData, error) {
+	var (
+		itx BlobTx
+		err error
+	)
+	if dec.ChainID == nil {
+		return nil, errors.New("missing required field 'chainId' in transaction")
+	}
+	if itx.ChainID, err = toUint256Checked(dec.ChainID); err != nil {
+		return nil, fmt.Errorf("invalid field 'chainId' in transaction: %w", err)
+	}
+	if dec.Nonce == nil {
+		return nil, errors.New("missing required field 'nonce' in transaction")
+	}
+	itx.Nonce = uint64(*dec.Nonce)
+	if dec.To != nil {
+		itx.To = dec.To
+	}
+	if dec.Gas == nil {
+		return nil, errors.New("missing required field 'gas' for txdata")
+	}
+	itx.Gas = uint64(*dec.Gas)
+	if dec.MaxPriorityFeePerGas == nil {
+		return nil, errors.New("missing required field 'maxPriorityFeePerGas' for txdata")
+	}
+	if itx.GasTipCap, err = toUint256Checked(dec.MaxPriorityFeePerGas); err != nil {
+		return nil, fmt.Errorf("invalid field 'maxPriorityFeePerGas' in transaction: %w", err)
+	}
+	if dec.MaxFeePerGas == nil {
+		return nil, errors.New("missing required field 'maxFeePerGas' for txdata")
+	}
+	if itx.GasFeeCap, err = toUint256Checked(dec.MaxFeePerGas); err != nil {
+		return nil, fmt.Errorf("invalid field 'maxFeePerGas' in transaction: %w", err)
+	}
+	if dec.MaxFeePerDataGas == nil {
+		return nil, errors.New("missing required field 'maxFeePerDataGas' for txdata")
+	}
+	if itx.BlobFeeCap, err = toUint256Checked(dec.MaxFeePerDataGas); err != nil {
+		return nil, fmt.Errorf("invalid field 'maxFeePerDataGas' in transaction: %w", err)
+	}
+	if dec.Value == nil {
+		return nil, errors.New("missing required field 'value' in transaction")
+	}
+	if itx.Value, err = toUint256Checked(dec.Value); err != nil {
+		return nil, fmt.Errorf("invalid field 'value' in transaction: %w", err)
+	}
+	if dec.Input == nil {
+		return nil, errors.New("missing required field 'input' in transaction")
+	}
+	itx.Data = common.CopyBytes(*dec.Input)
+	if dec.V == nil {
+		return nil, errors.New("missing required field 'v' in transaction")
+	}
+	if dec.AccessList != nil {
+		if err := checkAccessListSize(*dec.AccessList); err != nil {
+			return nil, err
 		}
-		itx.R = uint256.MustFromBig((*big.Int)(dec.R))
-		if dec.S == nil {
-			return errors.New("missing required field 's' in transaction")
+		itx.AccessList = fillStorageKeys(*dec.AccessList)
+	}
+	if dec.BlobVersionedHashes == nil {
+		return nil, errors.New("missing required field 'blobVersionedHashes' in transaction")
+	}
+	if n := len(dec.BlobVersionedHashes); n == 0 || n > MaxBlobVersionedHashesPerTx {
+		return nil, fmt.Errorf("invalid number of blob versioned hashes in transaction: have %d, want 1 to %d", n, MaxBlobVersionedHashesPerTx)
+	}
+	itx.BlobHashes = dec.BlobVersionedHashes
+	sidecar, err := dec.blobTxSidecar(itx.BlobHashes)
+	if err != nil {
+		return nil, err
+	}
+	itx.Sidecar = sidecar
+	if itx.V, err = toUint256Checked(dec.V); err != nil {
+		return nil, fmt.Errorf("invalid field 'v' in transaction: %w", err)
+	}
+	if dec.R == nil {
+		return nil, errors.New("missing required field 'r' in transaction")
+	}
+	if itx.R, err = toUint256Checked(dec.R); err != nil {
+		return nil, fmt.Errorf("invalid field 'r' in transaction: %w", err)
+	}
+	if dec.S == nil {
+		return nil, errors.New("missing required field 's' in transaction")
+	}
+	if itx.S, err = toUint256Checked(dec.S); err != nil {
+		return nil, fmt.Errorf("invalid field 's' in transaction: %w", err)
+	}
+	withSignature := itx.V.Sign() != 0 || itx.R.Sign() != 0 || itx.S.Sign() != 0
+	if withSignature {
+		if err := sanityCheckSignature(itx.V.ToBig(), itx.R.ToBig(), itx.S.ToBig(), false); err != nil {
+			return nil, err
 		}
-		itx.S = uint256.MustFromBig((*big.Int)(dec.S))
-		withSignature := itx.V.Sign() != 0 || itx.R.Sign() != 0 || itx.S.Sign() != 0
-		if withSignature {
-			if err := sanityCheckSignature(itx.V.ToBig(), itx.R.ToBig(), itx.S.ToBig(), false); err != nil {
-				return err
+	}
+	return &itx, nil
+}
+
+// unmarshalDepositTxJSON verifies the JSON fields of a DepositTx and decodes them.
+func unmarshalDepositTxJSON(dec *TxJSON) (TxData, error) {
+	if dec.AccessList != nil || dec.MaxFeePerGas != nil ||
+		dec.MaxPriorityFeePerGas != nil {
+		return nil, errors.New("unexpected field(s) in deposit transaction")
+	}
+	if dec.GasPrice != nil && dec.GasPrice.ToInt().Cmp(common.Big0) != 0 {
+		return nil, errors.New("deposit transaction GasPrice must be 0")
+	}
+	if (dec.V != nil && dec.V.ToInt().Cmp(common.Big0) != 0) ||
+		(dec.R != nil && dec.R.ToInt().Cmp(common.Big0) != 0) ||
+		(dec.S != nil && dec.S.ToInt().Cmp(common.Big0) != 0) {
+		return nil, errors.New("deposit transaction signature must be 0 or unset")
+	}
+	var itx DepositTx
+	if dec.To != nil {
+		itx.To = dec.To
+	}
+	if dec.Gas == nil {
+		return nil, errors.New("missing required field 'gas' for txdata")
+	}
+	itx.Gas = uint64(*dec.Gas)
+	if dec.Value == nil {
+		return nil, errors.New("missing required field 'value' in transaction")
+	}
+	itx.Value = (*big.Int)(dec.Value)
+	// mint may be omitted or nil if there is nothing to mint.
+	itx.Mint = (*big.Int)(dec.Mint)
+	if dec.Input == nil {
+		return nil, errors.New("missing required field 'input' in transaction")
+	}
+	itx.Data = common.CopyBytes(*dec.Input)
+	if dec.From == nil {
+		return nil, errors.New("missing required field 'from' in transaction")
+	}
+	itx.From = *dec.From
+	if dec.SourceHash == nil {
+		return nil, errors.New("missing required field 'sourceHash' in transaction")
+	}
+	itx.SourceHash = *dec.SourceHash
+	// IsSystemTx may be omitted. Defaults to false.
+	if dec.IsSystemTx != nil {
+		itx.IsSystemTransaction = *dec.IsSystemTx
+	}
+
+	log.Info("op-geth parsed DepositTransaction", "itx", itx)
+	if dec.Nonce != nil {
+		return &depositTxWithNonce{DepositTx: itx, EffectiveNonce: uint64(*dec.Nonce)}, nil
+	}
+	return &itx, nil
+}
+
+// UnmarshalBlobTxNetworkJSON decodes a blob transaction in its network form,
+// i.e. the transaction fields together with the 'blobs', 'commitments' and
+// 'proofs' sidecar arrays. The number of entries in each of the sidecar arrays
+// must match the number of blob versioned hashes in the transaction, and the
+// commitments must match the blob versioned hashes.
+func UnmarshalBlobTxNetworkJSON(input []byte) (*Transaction, *BlobTxSidecar, error) {
+	tx := new(Transaction)
+	if err := tx.UnmarshalJSON(input); err != nil {
+		return nil, nil, err
+	}
+	if tx.Type() != BlobTxType {
+		return nil, nil, fmt.Errorf("%w: have type %d, want %d", ErrInvalidTxType, tx.Type(), BlobTxType)
+	}
+	sidecar := tx.BlobTxSidecar()
+	if sidecar == nil {
+		return nil, nil, errors.New("missing required field 'blobs' in transaction")
+	}
+	return tx, sidecar, nil
+}
+
+// checkAccessListSize verifies that the access list is within the limits of
+// MaxAccessListEntries and MaxStorageKeysPerEntry.
+func checkAccessListSize(al AccessList) error {
+	if MaxAccessListEntries > 0 && len(al) > MaxAccessListEntries {
+		return fmt.Errorf("%w: have %d entries, max %d", ErrAccessListTooLarge, len(al), MaxAccessListEntries)
+	}
+	if MaxStorageKeysPerEntry > 0 {
+		for i, tuple := range al {
+			if len(tuple.StorageKeys) > MaxStorageKeysPerEntry {
+				return fmt.Errorf("%w: entry %d has %d storage keys, max %d", ErrAccessListTooLarge, i, len(tuple.StorageKeys), MaxStorageKeysPerEntry)
 			}
 		}
+	}
+	return nil
+}
 
-	case DepositTxType:
-		if dec.AccessList != nil || dec.MaxFeePerGas != nil ||
-			dec.MaxPriorityFeePerGas != nil {
-			return errors.New("unexpected field(s) in deposit transaction")
-		}
-		if dec.GasPrice != nil && dec.GasPrice.ToInt().Cmp(common.Big0) != 0 {
-			return errors.New("deposit transaction GasPrice must be 0")
-		}
-		if (dec.V != nil && dec.V.ToInt().Cmp(common.Big0) != 0) ||
-			(dec.R != nil && dec.R.ToInt().Cmp(common.Big0) != 0) ||
-			(dec.S != nil && dec.S.ToInt().Cmp(common.Big0) != 0) {
-			return errors.New("deposit transaction signature must be 0 or unset")
-		}
-		var itx DepositTx
-		inner = &itx
-		if dec.To != nil {
-			itx.To = dec.To
+// fillStorageKeys replaces omitted storage key lists in the access list with
+// empty ones. EIP-2930 permits entries without storage keys, and some producers
+// leave out the field instead of sending an empty list.
+func fillStorageKeys(al AccessList) AccessList {
+	for i := range al {
+		if al[i].StorageKeys == nil {
+			al[i].StorageKeys = []common.Hash{}
 		}
-		if dec.Gas == nil {
-			return errors.New("missing required field 'gas' for txdata")
-		}
-		itx.Gas = uint64(*dec.Gas)
-		if dec.Value == nil {
-			return errors.New("missing required field 'value' in transaction")
-		}
-		itx.Value = (*big.Int)(dec.Value)
-		// mint may be omitted or nil if there is nothing to mint.
-		itx.Mint = (*big.Int)(dec.Mint)
-		if dec.Input == nil {
-			return errors.New("missing required field 'input' in transaction")
-		}
-		itx.Data = *dec.Input
-		if dec.From == nil {
-			return errors.New("missing required field 'from' in transaction")
+	}
+	return al
+}
+
+// toUint256Checked converts a decoded quantity into a uint256, returning an error
+// instead of panicking if it does not fit into 256 bits.
+func toUint256Checked(b *hexutil.Big) (*uint256.Int, error) {
+	v, overflow := uint256.FromBig((*big.Int)(b))
+	if overflow {
+		return nil, fmt.Errorf("%d-bit quantity exceeds 256 bits", (*big.Int)(b).BitLen())
+	}
+	return v, nil
+}
+
+// blobTxSidecar converts the decoded sidecar arrays into a BlobTxSidecar,
+// checking that every array holds one correctly sized entry per blob hash and
+// that the commitments match the blob hashes. It returns nil if the JSON object
+// carries no sidecar.
+func (dec *TxJSON) blobTxSidecar(hashes []common.Hash) (*BlobTxSidecar, error) {
+	n := len(hashes)
+	if dec.Blobs == nil && dec.Commitments == nil && dec.Proofs == nil {
+		return nil, nil
+	}
+	if dec.Blobs == nil {
+		return nil, errors.New("missing required field 'blobs' in transaction")
+	}
+	if dec.Commitments == nil {
+		return nil, errors.New("missing required field 'commitments' in transaction")
+	}
+	if dec.Proofs == nil {
+		return nil, errors.New("missing required field 'proofs' in transaction")
+	}
+	if len(dec.Blobs) != n {
+		return nil, fmt.Errorf("invalid number of %d blobs compared to %d blob hashes", len(dec.Blobs), n)
+	}
+	if len(dec.Commitments) != n {
+		return nil, fmt.Errorf("invalid number of %d blob commitments compared to %d blob hashes", len(dec.Commitments), n)
+	}
+	if len(dec.Proofs) != n {
+		return nil, fmt.Errorf("invalid number of %d blob proofs compared to %d blob hashes", len(dec.Proofs), n)
+	}
+	sidecar := &BlobTxSidecar{
+		Blobs:       make([]kzg4844.Blob, n),
+		Commitments: make([]kzg4844.Commitment, n),
+		Proofs:      make([]kzg4844.Proof, n),
+	}
+	for i := 0; i < n; i++ {
+		if len(dec.Blobs[i]) != len(kzg4844.Blob{}) {
+			return nil, fmt.Errorf("invalid blob %d length: have %d, want %d", i, len(dec.Blobs[i]), len(kzg4844.Blob{}))
 		}
-		itx.From = *dec.From
-		if dec.SourceHash == nil {
-			return errors.New("missing required field 'sourceHash' in transaction")
+		copy(sidecar.Blobs[i][:], dec.Blobs[i])
+		if len(dec.Commitments[i]) != len(kzg4844.Commitment{}) {
+			return nil, fmt.Errorf("invalid blob commitment %d length: have %d, want %d", i, len(dec.Commitments[i]), len(kzg4844.Commitment{}))
 		}
-		itx.SourceHash = *dec.SourceHash
-		// IsSystemTx may be omitted. Defaults to false.
-		if dec.IsSystemTx != nil {
-			itx.IsSystemTransaction = *dec.IsSystemTx
+		copy(sidecar.Commitments[i][:], dec.Commitments[i])
+		if len(dec.Proofs[i]) != len(kzg4844.Proof{}) {
+			return nil, fmt.Errorf("invalid blob proof %d length: have %d, want %d", i, len(dec.Proofs[i]), len(kzg4844.Proof{}))
 		}
-
-		if dec.Nonce != nil {
-			inner = &depositTxWithNonce{DepositTx: itx, EffectiveNonce: uint64(*dec.Nonce)}
+		copy(sidecar.Proofs[i][:], dec.Proofs[i])
+	}
+	for i, h := range sidecar.BlobHashes() {
+		if h != hashes[i] {
+			return nil, fmt.Errorf("invalid blob commitment %d: has versioned hash %v, want %v", i, h, hashes[i])
 		}
-		log.Info("op-geth parsed DepositTransaction", "itx", itx)
-	default:
-		return ErrTxTypeNotSupported
 	}
-
-	// Now set the inner transaction.
-	tx.setDecoded(inner, 0)
-
-	// TODO: check hash here?
-	return nil
+	return sidecar, nil
 }
 
 type depositTxWithNonce struct {
@@ -1,11 +1,21 @@
 package types
 
 import (
+	"bytes"
 	"encoding/json"
+	"fmt"
 	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/crypto/kzg4844"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/holiman/uint256"
 	"github.com/stretchr/testify/require"
 )
 
@@ -101,3 +111,896 @@ func TestTransactionUnmarshalJSON(t *testing.T) {
 		})
 	}
 }
+
+// blobTxNetworkJSON returns the JSON encoding of tx with the given number of
+// blobs, commitments and proofs attached as its network-form sidecar.
+func blobTxNetworkJSON(t *testing.T, tx *Transaction, blobs, commitments, proofs int) []byte {
+	enc, err := tx.MarshalJSON()
+	require.NoError(t, err)
+
+	var obj map[string]interface{}
+	require.NoError(t, json.Unmarshal(enc, &obj))
+	fill := func(n, size int) []hexutil.Bytes {
+		list := make([]hexutil.Bytes, n)
+		for i := range list {
+			list[i] = make(hexutil.Bytes, size)
+		}
+		return list
+	}
+	if blobs >= 0 {
+		obj["blobs"] = fill(blobs, len(kzg4844.Blob{}))
+	}
+	if commitments >= 0 {
+		obj["commitments"] = fill(commitments, len(kzg4844.Commitment{}))
+	}
+	if proofs >= 0 {
+		obj["proofs"] = fill(proofs, len(kzg4844.Proof{}))
+	}
+	enc, err = json.Marshal(obj)
+	require.NoError(t, err)
+	return enc
+}
+
+func TestUnmarshalBlobTxNetworkJSON(t *testing.T) {
+	// The blob hashes are the versioned hashes of the zero commitments set by
+	// blobTxNetworkJSON.
+	tx := NewTx(&BlobTx{
+		ChainID:    uint256.NewInt(1),
+		Nonce:      1,
+		Gas:        21000,
+		To:         &common.Address{0x01},
+		BlobHashes: []common.Hash{blobHash(&kzg4844.Commitment{}), blobHash(&kzg4844.Commitment{})},
+	})
+
+	t.Run("Matching counts", func(t *testing.T) {
+		got, sidecar, err := UnmarshalBlobTxNetworkJSON(blobTxNetworkJSON(t, tx, 2, 2, 2))
+		require.NoError(t, err)
+		require.Equal(t, tx.Hash(), got.Hash())
+		require.Len(t, sidecar.Blobs, 2)
+		require.Len(t, sidecar.Commitments, 2)
+		require.Len(t, sidecar.Proofs, 2)
+	})
+	t.Run("Mismatched counts", func(t *testing.T) {
+		_, _, err := UnmarshalBlobTxNetworkJSON(blobTxNetworkJSON(t, tx, 2, 1, 2))
+		require.ErrorContains(t, err, "invalid number of 1 blob commitments compared to 2 blob hashes")
+	})
+	t.Run("Mismatched commitments", func(t *testing.T) {
+		other := NewTx(&BlobTx{
+			ChainID:    uint256.NewInt(1),
+			Nonce:      1,
+			Gas:        21000,
+			To:         &common.Address{0x01},
+			BlobHashes: []common.Hash{{0x01}, {0x02}},
+		})
+		_, _, err := UnmarshalBlobTxNetworkJSON(blobTxNetworkJSON(t, other, 2, 2, 2))
+		require.ErrorContains(t, err, "invalid blob commitment 0")
+	})
+	t.Run("Missing sidecar", func(t *testing.T) {
+		_, _, err := UnmarshalBlobTxNetworkJSON(blobTxNetworkJSON(t, tx, -1, -1, -1))
+		require.ErrorContains(t, err, "missing required field 'blobs'")
+	})
+}
+
+func TestTransactionMarshalJSONDepositTo(t *testing.T) {
+	to := common.HexToAddress("0x1")
+	tests := []struct {
+		name     string
+		to       *common.Address
+		expected string
+	}{
+		{name: "Creation", to: nil, expected: `"to":null`},
+		{name: "Call", to: &to, expected: `"to":"0x0000000000000000000000000000000000000001"`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tx := NewTx(&DepositTx{
+				SourceHash: common.HexToHash("0x1234"),
+				From:       common.HexToAddress("0x2"),
+				To:         test.to,
+				Value:      big.NewInt(1),
+				Gas:        1000,
+			})
+			enc, err := tx.MarshalJSON()
+			require.NoError(t, err)
+			require.Contains(t, string(enc), test.expected)
+		})
+	}
+}
+
+func TestTransactionUnmarshalJSONInputLimit(t *testing.T) {
+	// Large calldata is accepted by default.
+	large := NewTx(&LegacyTx{Nonce: 1, Gas: 1000, GasPrice: big.NewInt(1), Data: make([]byte, 2*1024*1024)})
+	enc, err := json.Marshal(large)
+	require.NoError(t, err)
+	require.NoError(t, new(Transaction).UnmarshalJSON(enc))
+
+	defer func(max int) { MaxTxInputBytes = max }(MaxTxInputBytes)
+	MaxTxInputBytes = 256 * 1024
+	txs := map[string]func(data []byte) TxData{
+		"Legacy": func(data []byte) TxData {
+			return &LegacyTx{Nonce: 1, Gas: 1000, GasPrice: big.NewInt(1), Data: data}
+		},
+		"Deposit": func(data []byte) TxData {
+			return &DepositTx{SourceHash: common.HexToHash("0x1234"), From: common.HexToAddress("0x1"), Gas: 1000, Data: data}
+		},
+	}
+	for name, newTx := range txs {
+		t.Run(name, func(t *testing.T) {
+			enc, err := json.Marshal(NewTx(newTx(make([]byte, MaxTxInputBytes))))
+			require.NoError(t, err)
+			require.NoError(t, new(Transaction).UnmarshalJSON(enc))
+
+			enc, err = json.Marshal(NewTx(newTx(make([]byte, MaxTxInputBytes+1))))
+			require.NoError(t, err)
+			require.ErrorIs(t, new(Transaction).UnmarshalJSON(enc), ErrTxInputTooLarge)
+		})
+	}
+}
+
+func TestTransactionUnmarshalJSONInitCodeLimit(t *testing.T) {
+	defer func(enforce bool) { EnforceMaxInitCodeSize = enforce }(EnforceMaxInitCodeSize)
+	newTx := func(to *common.Address, size int) []byte {
+		enc, err := json.Marshal(NewTx(&DepositTx{SourceHash: common.HexToHash("0x1234"), From: common.HexToAddress("0x1"), To: to, Gas: 1000, Data: make([]byte, size)}))
+		require.NoError(t, err)
+		return enc
+	}
+	var (
+		atLimit   = newTx(nil, params.MaxInitCodeSize)
+		overLimit = newTx(nil, params.MaxInitCodeSize+1)
+		call      = newTx(&common.Address{0x01}, params.MaxInitCodeSize+1)
+	)
+
+	EnforceMaxInitCodeSize = false
+	require.NoError(t, new(Transaction).UnmarshalJSON(overLimit))
+
+	EnforceMaxInitCodeSize = true
+	require.NoError(t, new(Transaction).UnmarshalJSON(atLimit))
+	require.ErrorIs(t, new(Transaction).UnmarshalJSON(overLimit), ErrTxInitCodeTooLarge)
+	require.NoError(t, new(Transaction).UnmarshalJSON(call))
+}
+
+func TestMarshalTxJSONWithBaseFee(t *testing.T) {
+	tests := []struct {
+		name     string
+		tx       *Transaction
+		baseFee  *big.Int
+		expected *big.Int
+	}{
+		{
+			name:     "Dynamic fee, tip capped",
+			tx:       NewTx(&DynamicFeeTx{ChainID: big.NewInt(1), GasTipCap: big.NewInt(2), GasFeeCap: big.NewInt(100)}),
+			baseFee:  big.NewInt(10),
+			expected: big.NewInt(12),
+		},
+		{
+			name:     "Dynamic fee, fee capped",
+			tx:       NewTx(&DynamicFeeTx{ChainID: big.NewInt(1), GasTipCap: big.NewInt(2), GasFeeCap: big.NewInt(100)}),
+			baseFee:  big.NewInt(99),
+			expected: big.NewInt(100),
+		},
+		{
+			name:     "Deposit",
+			tx:       NewTx(&DepositTx{SourceHash: common.HexToHash("0x1234"), From: common.HexToAddress("0x1"), Gas: 1000}),
+			baseFee:  big.NewInt(10),
+			expected: big.NewInt(0),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			enc, err := MarshalTxJSONWithBaseFee(test.tx, test.baseFee)
+			require.NoError(t, err)
+
+			var dec struct {
+				EffectiveGasPrice *hexutil.Big `json:"effectiveGasPrice"`
+			}
+			require.NoError(t, json.Unmarshal(enc, &dec))
+			require.Zero(t, test.expected.Cmp(dec.EffectiveGasPrice.ToInt()))
+
+			var tx Transaction
+			require.NoError(t, tx.UnmarshalJSON(enc))
+			require.Equal(t, test.tx.Hash(), tx.Hash())
+		})
+	}
+}
+
+func TestUnmarshalCallObject(t *testing.T) {
+	tests := []struct {
+		name          string
+		json          string
+		expectedError string
+	}{
+		{
+			name: "Dynamic fee",
+			json: `{"type":"0x2","chainId":"0x1","to":"0x0000000000000000000000000000000000000001","gas":"0x5208","maxPriorityFeePerGas":"0x1","maxFeePerGas":"0x2","value":"0x3","input":"0x1234"}`,
+		},
+		{
+			name: "Legacy",
+			json: `{"type":"0x0","to":"0x0000000000000000000000000000000000000001","gasPrice":"0x1"}`,
+		},
+		{
+			name:          "Invalid to",
+			json:          `{"type":"0x0","to":"0x01","gasPrice":"0x1"}`,
+			expectedError: "hex string has length 2, want 40 for common.Address",
+		},
+		{
+			name:          "Invalid value",
+			json:          `{"type":"0x0","gasPrice":"0x1","value":"1"}`,
+			expectedError: "hex string without 0x prefix",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tx, err := UnmarshalCallObject([]byte(test.json))
+			if test.expectedError != "" {
+				require.ErrorContains(t, err, test.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			v, r, s := tx.RawSignatureValues()
+			require.Zero(t, v.Sign())
+			require.Zero(t, r.Sign())
+			require.Zero(t, s.Sign())
+			require.Equal(t, common.HexToAddress("0x1"), *tx.To())
+		})
+	}
+}
+
+func TestTransactionJSONBlobSidecar(t *testing.T) {
+	sidecar := &BlobTxSidecar{
+		Blobs:       []kzg4844.Blob{{0x01}, {0x02}},
+		Commitments: []kzg4844.Commitment{{0x03}, {0x04}},
+		Proofs:      []kzg4844.Proof{{0x05}, {0x06}},
+	}
+	for _, sc := range []*BlobTxSidecar{nil, sidecar} {
+		inner := &BlobTx{
+			ChainID:    uint256.NewInt(1),
+			Nonce:      1,
+			Gas:        21000,
+			To:         &common.Address{0x01},
+			BlobHashes: sidecar.BlobHashes(),
+			Sidecar:    sc,
+		}
+		tx := NewTx(inner)
+		enc, err := tx.MarshalJSON()
+		require.NoError(t, err)
+		require.Equal(t, sc != nil, bytes.Contains(enc, []byte(`"blobs"`)))
+
+		var dec Transaction
+		require.NoError(t, dec.UnmarshalJSON(enc))
+		require.Equal(t, tx.Hash(), dec.Hash())
+		require.Equal(t, sc, dec.BlobTxSidecar())
+		if sc != nil {
+			require.Equal(t, dec.BlobHashes(), dec.BlobTxSidecar().BlobHashes())
+		}
+	}
+
+	// A sidecar whose commitments do not match the blob hashes is rejected.
+	tx := NewTx(&BlobTx{
+		ChainID:    uint256.NewInt(1),
+		Nonce:      1,
+		Gas:        21000,
+		To:         &common.Address{0x01},
+		BlobHashes: []common.Hash{sidecar.BlobHashes()[1], sidecar.BlobHashes()[0]},
+		Sidecar:    sidecar,
+	})
+	enc, err := tx.MarshalJSON()
+	require.NoError(t, err)
+	require.ErrorContains(t, new(Transaction).UnmarshalJSON(enc), "invalid blob commitment 0")
+}
+
+func TestTransactionUnmarshalJSONWithChainID(t *testing.T) {
+	const depositJSON = `{"type":"0x7e",%s"gas":"0x1234","value":"0x1","input":"0x","to":null,"sourceHash":"0x0000000000000000000000000000000000000000000000000000000000001234","from":"0x0000000000000000000000000000000000000001"}`
+	tests := []struct {
+		name          string
+		chainID       string
+		expectedError string
+	}{
+		{name: "Omitted"},
+		{name: "Zero", chainID: `"chainId":"0x0",`},
+		{name: "Correct", chainID: `"chainId":"0xa",`},
+		{name: "Incorrect", chainID: `"chainId":"0xb",`, expectedError: "invalid chain id 11 in transaction, want 10"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var tx Transaction
+			err := tx.UnmarshalJSONWithChainID([]byte(fmt.Sprintf(depositJSON, test.chainID)), big.NewInt(10))
+			if test.expectedError == "" {
+				require.NoError(t, err)
+				require.True(t, tx.IsDepositTx())
+			} else {
+				require.EqualError(t, err, test.expectedError)
+			}
+		})
+	}
+
+	// The chain ID of other transaction types is checked too, and zero is not
+	// accepted for them.
+	for _, chainID := range []int64{0, 10} {
+		enc, err := NewTx(&DynamicFeeTx{ChainID: big.NewInt(chainID), Nonce: 1, Gas: 21000, GasTipCap: big.NewInt(1), GasFeeCap: big.NewInt(1), Value: big.NewInt(1)}).MarshalJSON()
+		require.NoError(t, err)
+		var tx Transaction
+		err = tx.UnmarshalJSONWithChainID(enc, big.NewInt(10))
+		if chainID == 10 {
+			require.NoError(t, err)
+			require.Equal(t, uint8(DynamicFeeTxType), tx.Type())
+		} else {
+			require.EqualError(t, err, "invalid chain id 0 in transaction, want 10")
+		}
+	}
+
+	// A nil chain ID is rejected instead of compared.
+	err := new(Transaction).UnmarshalJSONWithChainID([]byte(fmt.Sprintf(depositJSON, `"chainId":"0xa",`)), nil)
+	require.EqualError(t, err, "missing chain id to verify transaction against")
+}
+
+func TestTransactionSummaryJSON(t *testing.T) {
+	to := common.HexToAddress("0x2")
+	legacy := NewTx(&LegacyTx{Nonce: 1, GasPrice: big.NewInt(1), Gas: 21000, To: &to, Value: big.NewInt(10), Data: []byte{0x01}})
+	deposit := NewTx(&DepositTx{SourceHash: common.HexToHash("0x1234"), From: common.HexToAddress("0x1"), Gas: 1000, Value: big.NewInt(1), Data: []byte{0x01}})
+
+	enc, err := legacy.SummaryJSON()
+	require.NoError(t, err)
+	require.JSONEq(t, fmt.Sprintf(`{"type":"0x0","hash":"%s","to":"0x0000000000000000000000000000000000000002","gas":"0x5208","value":"0xa"}`, legacy.Hash().Hex()), string(enc))
+
+	enc, err = deposit.SummaryJSON()
+	require.NoError(t, err)
+	require.JSONEq(t, fmt.Sprintf(`{"type":"0x7e","hash":"%s","from":"0x0000000000000000000000000000000000000001","to":null,"gas":"0x3e8","value":"0x1"}`, deposit.Hash().Hex()), string(enc))
+}
+
+func TestTransactionUnmarshalBlobOverflow(t *testing.T) {
+	const blobJSON = `{"type":"0x3","chainId":"0x1","nonce":"0x0","to":"0x0000000000000000000000000000000000000001","gas":"0x5208","maxPriorityFeePerGas":"0x1","maxFeePerGas":"0x1","maxFeePerDataGas":"0x1","value":"%s","input":"0x","accessList":[],"blobVersionedHashes":["0x0100000000000000000000000000000000000000000000000000000000000000"],"v":"0x0","r":"0x0","s":"0x0"}`
+	var (
+		max      = new(big.Int).Sub(new(big.Int).Lsh(common.Big1, 256), common.Big1)
+		overflow = new(big.Int).Lsh(common.Big1, 256)
+		tx       Transaction
+	)
+	require.NoError(t, tx.UnmarshalJSON([]byte(fmt.Sprintf(blobJSON, hexutil.EncodeBig(max)))))
+	require.Zero(t, tx.Value().Cmp(max))
+
+	// JSON quantities are already capped at 256 bits by hexutil.
+	err := tx.UnmarshalJSON([]byte(fmt.Sprintf(blobJSON, hexutil.EncodeBig(overflow))))
+	require.ErrorContains(t, err, hexutil.ErrBig256Range.Error())
+
+	// Other encodings sharing the decoder are not, and must not panic.
+	var dec TxJSON
+	require.NoError(t, json.Unmarshal([]byte(fmt.Sprintf(blobJSON, "0x0")), &dec))
+	dec.Value = (*hexutil.Big)(overflow)
+	err = tx.decodeJSON(&dec)
+	require.EqualError(t, err, "invalid field 'value' in transaction: 257-bit quantity exceeds 256 bits")
+}
+
+func TestTransactionUnmarshalJSONNull(t *testing.T) {
+	for _, input := range []string{"null", " null\n"} {
+		require.EqualError(t, new(Transaction).UnmarshalJSON([]byte(input)), "transaction is null")
+		require.EqualError(t, new(Transaction).UnmarshalJSONLenient([]byte(input)), "transaction is null")
+		require.EqualError(t, new(Transaction).UnmarshalJSONUntrusted([]byte(input)), "transaction is null")
+	}
+	// A null transaction inside a larger structure.
+	var txs []Transaction
+	require.EqualError(t, json.Unmarshal([]byte(`[null]`), &txs), "transaction is null")
+}
+
+func TestDecodeTransactionFromReader(t *testing.T) {
+	data := bytes.Repeat([]byte{0xab}, 4*1024*1024)
+	deposit := NewTx(&DepositTx{SourceHash: common.HexToHash("0x1234"), From: common.HexToAddress("0x01"), Gas: 1000, Value: big.NewInt(1), Data: data})
+	enc, err := deposit.MarshalJSON()
+	require.NoError(t, err)
+
+	var want Transaction
+	require.NoError(t, want.UnmarshalJSON(enc))
+	have, err := DecodeTransactionFromReader(strings.NewReader(string(enc)))
+	require.NoError(t, err)
+	require.Equal(t, want.Hash(), have.Hash())
+	require.Equal(t, data, have.Data())
+
+	// Errors match the byte slice path.
+	_, err = DecodeTransactionFromReader(strings.NewReader("null"))
+	require.EqualError(t, err, "transaction is null")
+	_, err = DecodeTransactionFromReader(strings.NewReader(`{"type":"0x7e"}`))
+	require.EqualError(t, err, new(Transaction).UnmarshalJSON([]byte(`{"type":"0x7e"}`)).Error())
+}
+
+func TestTransactionUnmarshalJSONStrayBlobHashes(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+	}{
+		{
+			name: "Legacy",
+			json: `{"type":"0x0","nonce":"0x0","to":null,"gas":"0x5208","gasPrice":"0x1","value":"0x0","input":"0x","v":"0x0","r":"0x0","s":"0x0"%s}`,
+		},
+		{
+			name: "AccessList",
+			json: `{"type":"0x1","chainId":"0x1","nonce":"0x0","to":null,"gas":"0x5208","gasPrice":"0x1","value":"0x0","input":"0x","accessList":[],"v":"0x0","r":"0x0","s":"0x0"%s}`,
+		},
+		{
+			name: "DynamicFee",
+			json: `{"type":"0x2","chainId":"0x1","nonce":"0x0","to":null,"gas":"0x5208","maxPriorityFeePerGas":"0x1","maxFeePerGas":"0x1","value":"0x0","input":"0x","accessList":[],"v":"0x0","r":"0x0","s":"0x0"%s}`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require.NoError(t, new(Transaction).UnmarshalJSON([]byte(fmt.Sprintf(test.json, ""))))
+
+			stray := fmt.Sprintf(test.json, `,"blobVersionedHashes":["0x0100000000000000000000000000000000000000000000000000000000000000"]`)
+			require.EqualError(t, new(Transaction).UnmarshalJSON([]byte(stray)), "unexpected field 'blobVersionedHashes' in transaction")
+
+			empty := fmt.Sprintf(test.json, `,"blobVersionedHashes":[]`)
+			require.EqualError(t, new(Transaction).UnmarshalJSON([]byte(empty)), "unexpected field 'blobVersionedHashes' in transaction")
+		})
+	}
+}
+
+func TestTransactionUnmarshalJSONBlobHashCount(t *testing.T) {
+	const blobJSON = `{"type":"0x3","chainId":"0x1","nonce":"0x0","to":"0x0000000000000000000000000000000000000001","gas":"0x5208","maxPriorityFeePerGas":"0x1","maxFeePerGas":"0x1","maxFeePerDataGas":"0x1","value":"0x0","input":"0x","accessList":[],"blobVersionedHashes":%s,"v":"0x0","r":"0x0","s":"0x0"}`
+	hashes := func(n int) string {
+		enc, err := json.Marshal(make([]common.Hash, n))
+		require.NoError(t, err)
+		return string(enc)
+	}
+	require.Equal(t, 6, MaxBlobVersionedHashesPerTx)
+	for _, n := range []int{1, MaxBlobVersionedHashesPerTx} {
+		var tx Transaction
+		require.NoError(t, tx.UnmarshalJSON([]byte(fmt.Sprintf(blobJSON, hashes(n)))))
+		require.Len(t, tx.BlobHashes(), n)
+	}
+	err := new(Transaction).UnmarshalJSON([]byte(fmt.Sprintf(blobJSON, hashes(0))))
+	require.EqualError(t, err, "invalid number of blob versioned hashes in transaction: have 0, want 1 to 6")
+	err = new(Transaction).UnmarshalJSON([]byte(fmt.Sprintf(blobJSON, hashes(MaxBlobVersionedHashesPerTx+1))))
+	require.EqualError(t, err, "invalid number of blob versioned hashes in transaction: have 7, want 1 to 6")
+}
+
+// TestTransactionJSONGolden pins the exact JSON encoding, including the key
+// order, of every transaction type.
+func TestTransactionJSONGolden(t *testing.T) {
+	names := []string{"legacy", "accesslist", "dynamicfee", "blob", "deposit"}
+	txs := encodingTestTxs(t)
+	require.Len(t, txs, len(names))
+	for i, tx := range txs {
+		t.Run(names[i], func(t *testing.T) {
+			want, err := os.ReadFile(filepath.Join("testdata", "txjson", names[i]+".json"))
+			require.NoError(t, err)
+			have, err := tx.MarshalJSON()
+			require.NoError(t, err)
+			require.Equal(t, string(bytes.TrimSpace(want)), string(have))
+
+			// Decoding and re-encoding must reproduce the same bytes.
+			var dec Transaction
+			require.NoError(t, dec.UnmarshalJSON(have))
+			again, err := dec.MarshalJSON()
+			require.NoError(t, err)
+			require.Equal(t, string(have), string(again))
+		})
+	}
+}
+
+func TestTransactionUnmarshalJSONInputNotAliased(t *testing.T) {
+	for _, tx := range encodingTestTxs(t) {
+		t.Run(fmt.Sprintf("type%d", tx.Type()), func(t *testing.T) {
+			want := common.CopyBytes(tx.Data())
+			enc, err := tx.MarshalJSON()
+			require.NoError(t, err)
+
+			// Neither the intermediate decoding buffer...
+			var raw TxJSON
+			require.NoError(t, json.Unmarshal(enc, &raw))
+			var dec Transaction
+			require.NoError(t, dec.decodeJSON(&raw))
+			for i := range *raw.Input {
+				(*raw.Input)[i] = 0xff
+			}
+			require.True(t, bytes.Equal(want, dec.Data()), "calldata changed with decoding buffer")
+
+			// ...nor the JSON input may be aliased by the decoded calldata.
+			require.NoError(t, dec.UnmarshalJSON(enc))
+			for i := range enc {
+				enc[i] = 0
+			}
+			require.True(t, bytes.Equal(want, dec.Data()), "calldata changed with JSON input")
+		})
+	}
+}
+
+func TestMarshalTxJSONChecksummed(t *testing.T) {
+	var (
+		from = common.HexToAddress("0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed")
+		to   = common.HexToAddress("0xfb6916095ca1df60bb79ce92ce3ea74c37c5d359")
+	)
+	tests := []struct {
+		name string
+		tx   *Transaction
+		from *common.Address
+	}{
+		{
+			name: "Deposit",
+			tx:   NewTx(&DepositTx{SourceHash: common.HexToHash("0x1234"), From: from, To: &to, Gas: 1000, Value: big.NewInt(1)}),
+			from: &from,
+		},
+		{
+			name: "Legacy",
+			tx:   NewTx(&LegacyTx{To: &to, Gas: 21000, GasPrice: big.NewInt(1), Value: big.NewInt(1), V: big.NewInt(0), R: big.NewInt(0), S: big.NewInt(0)}),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			enc, err := MarshalTxJSONChecksummed(test.tx)
+			require.NoError(t, err)
+			var fields map[string]interface{}
+			require.NoError(t, json.Unmarshal(enc, &fields))
+			require.Equal(t, "0xfB6916095ca1df60bB79Ce92cE3Ea74c37c5d359", fields["to"])
+			if test.from != nil {
+				require.Equal(t, "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed", fields["from"])
+			} else {
+				require.NotContains(t, fields, "from")
+			}
+
+			// The result decodes to the same transaction.
+			var dec Transaction
+			require.NoError(t, dec.UnmarshalJSON(enc))
+			require.Equal(t, test.tx.Hash(), dec.Hash())
+
+			// The standard encoding is unaffected.
+			std, err := test.tx.MarshalJSON()
+			require.NoError(t, err)
+			require.Contains(t, string(std), `"to":"0xfb6916095ca1df60bb79ce92ce3ea74c37c5d359"`)
+		})
+	}
+
+	// Contract creations keep a null 'to'.
+	enc, err := MarshalTxJSONChecksummed(NewTx(&LegacyTx{Gas: 21000, GasPrice: big.NewInt(1)}))
+	require.NoError(t, err)
+	require.Contains(t, string(enc), `"to":null`)
+}
+
+func TestMarshalTxJSONDecimalType(t *testing.T) {
+	to := common.HexToAddress("0x01")
+	for _, tx := range []*Transaction{
+		NewTx(&DepositTx{SourceHash: common.HexToHash("0x1234"), From: common.HexToAddress("0x02"), To: &to, Gas: 1000, Value: big.NewInt(1)}),
+		NewTx(&DynamicFeeTx{ChainID: big.NewInt(1), To: &to, Gas: 21000, GasTipCap: big.NewInt(1), GasFeeCap: big.NewInt(1), Value: big.NewInt(1), V: big.NewInt(0), R: big.NewInt(0), S: big.NewInt(0)}),
+	} {
+		enc, err := MarshalTxJSONDecimalType(tx)
+		require.NoError(t, err)
+		std, err := tx.MarshalJSON()
+		require.NoError(t, err)
+		require.Contains(t, string(enc), fmt.Sprintf(`"type":%d`, tx.Type()))
+		require.Contains(t, string(std), fmt.Sprintf(`"type":"%#x"`, tx.Type()))
+
+		// All other fields are the same as in the standard encoding.
+		var have, want map[string]interface{}
+		require.NoError(t, json.Unmarshal(enc, &have))
+		require.NoError(t, json.Unmarshal(std, &want))
+		delete(have, "type")
+		delete(want, "type")
+		require.Equal(t, want, have)
+	}
+}
+
+func TestTransactionUnmarshalJSONAccessListLimits(t *testing.T) {
+	defer func(entries, keys int) {
+		MaxAccessListEntries, MaxStorageKeysPerEntry = entries, keys
+	}(MaxAccessListEntries, MaxStorageKeysPerEntry)
+	MaxAccessListEntries, MaxStorageKeysPerEntry = 2, 3
+
+	accessList := func(entries, keys int) AccessList {
+		al := make(AccessList, entries)
+		for i := range al {
+			al[i].StorageKeys = make([]common.Hash, keys)
+		}
+		return al
+	}
+	encode := func(txdata TxData) []byte {
+		enc, err := NewTx(txdata).MarshalJSON()
+		require.NoError(t, err)
+		return enc
+	}
+	tests := []struct {
+		name   string
+		txdata func(al AccessList) TxData
+	}{
+		{
+			name: "AccessList",
+			txdata: func(al AccessList) TxData {
+				return &AccessListTx{ChainID: big.NewInt(1), GasPrice: big.NewInt(1), Gas: 21000, Value: big.NewInt(0), AccessList: al, V: big.NewInt(0), R: big.NewInt(0), S: big.NewInt(0)}
+			},
+		},
+		{
+			name: "DynamicFee",
+			txdata: func(al AccessList) TxData {
+				return &DynamicFeeTx{ChainID: big.NewInt(1), GasTipCap: big.NewInt(1), GasFeeCap: big.NewInt(1), Gas: 21000, Value: big.NewInt(0), AccessList: al, V: big.NewInt(0), R: big.NewInt(0), S: big.NewInt(0)}
+			},
+		},
+		{
+			name: "Blob",
+			txdata: func(al AccessList) TxData {
+				return &BlobTx{ChainID: uint256.NewInt(1), GasTipCap: uint256.NewInt(1), GasFeeCap: uint256.NewInt(1), BlobFeeCap: uint256.NewInt(1), Gas: 21000, Value: uint256.NewInt(0), AccessList: al,
+					BlobHashes: []common.Hash{{0x01}}, V: uint256.NewInt(0), R: uint256.NewInt(0), S: uint256.NewInt(0)}
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var tx Transaction
+			require.NoError(t, tx.UnmarshalJSON(encode(test.txdata(accessList(2, 3)))))
+			require.Len(t, tx.AccessList(), 2)
+
+			err := tx.UnmarshalJSON(encode(test.txdata(accessList(3, 0))))
+			require.ErrorIs(t, err, ErrAccessListTooLarge)
+			require.ErrorContains(t, err, "have 3 entries, max 2")
+
+			err = tx.UnmarshalJSON(encode(test.txdata(accessList(1, 4))))
+			require.ErrorIs(t, err, ErrAccessListTooLarge)
+			require.ErrorContains(t, err, "entry 0 has 4 storage keys, max 3")
+		})
+	}
+}
+
+func TestTransactionUnmarshalJSONAccessListStorageKeys(t *testing.T) {
+	const (
+		accessListJSON = `{"type":"0x1","chainId":"0x1","nonce":"0x0","to":null,"gas":"0x5208","gasPrice":"0x1","value":"0x0","input":"0x","accessList":[%s],"v":"0x0","r":"0x0","s":"0x0"}`
+		dynamicFeeJSON = `{"type":"0x2","chainId":"0x1","nonce":"0x0","to":null,"gas":"0x5208","maxPriorityFeePerGas":"0x1","maxFeePerGas":"0x1","value":"0x0","input":"0x","accessList":[%s],"v":"0x0","r":"0x0","s":"0x0"}`
+		blobJSON       = `{"type":"0x3","chainId":"0x1","nonce":"0x0","to":"0x0000000000000000000000000000000000000001","gas":"0x5208","maxPriorityFeePerGas":"0x1","maxFeePerGas":"0x1","maxFeePerDataGas":"0x1","value":"0x0","input":"0x","accessList":[%s],"blobVersionedHashes":["0x0100000000000000000000000000000000000000000000000000000000000000"],"v":"0x0","r":"0x0","s":"0x0"}`
+
+		omitted   = `{"address":"0x0000000000000000000000000000000000000002"}`
+		empty     = `{"address":"0x0000000000000000000000000000000000000002","storageKeys":[]}`
+		populated = `{"address":"0x0000000000000000000000000000000000000002","storageKeys":["0x0000000000000000000000000000000000000000000000000000000000000003"]}`
+	)
+	for _, format := range []string{accessListJSON, dynamicFeeJSON, blobJSON} {
+		var omittedTx, emptyTx, populatedTx Transaction
+		require.NoError(t, omittedTx.UnmarshalJSON([]byte(fmt.Sprintf(format, omitted))))
+		require.NoError(t, emptyTx.UnmarshalJSON([]byte(fmt.Sprintf(format, empty))))
+		require.NoError(t, populatedTx.UnmarshalJSON([]byte(fmt.Sprintf(format, populated))))
+
+		// An omitted key list decodes like an empty one.
+		require.Equal(t, AccessList{{Address: common.HexToAddress("0x02"), StorageKeys: []common.Hash{}}}, omittedTx.AccessList())
+		require.Equal(t, emptyTx.AccessList(), omittedTx.AccessList())
+		require.Equal(t, emptyTx.Hash(), omittedTx.Hash())
+		enc, err := omittedTx.MarshalJSON()
+		require.NoError(t, err)
+		require.Contains(t, string(enc), `"storageKeys":[]`)
+
+		require.Equal(t, []common.Hash{common.HexToHash("0x03")}, populatedTx.AccessList()[0].StorageKeys)
+		require.NotEqual(t, emptyTx.Hash(), populatedTx.Hash())
+	}
+}
+
+func TestTransactionUnmarshalJSONLegacyChainID(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	tx, err := SignNewTx(key, NewEIP155Signer(big.NewInt(5)), &LegacyTx{Gas: 21000, GasPrice: big.NewInt(1), Value: big.NewInt(1)})
+	require.NoError(t, err)
+	enc, err := tx.MarshalJSON()
+	require.NoError(t, err)
+	withChainID := func(enc []byte, id string) []byte {
+		return bytes.Replace(enc, []byte(`{"type":"0x0",`), []byte(`{"type":"0x0","chainId":"`+id+`",`), 1)
+	}
+
+	// Omitted chain ID.
+	var dec Transaction
+	require.NoError(t, dec.UnmarshalJSON(enc))
+	require.Equal(t, tx.Hash(), dec.Hash())
+
+	// Matching chain ID.
+	require.NoError(t, dec.UnmarshalJSON(withChainID(enc, "0x5")))
+	require.Equal(t, tx.Hash(), dec.Hash())
+
+	// Mismatching chain ID.
+	require.EqualError(t, dec.UnmarshalJSON(withChainID(enc, "0x6")), "invalid chain id 6 in legacy transaction, signature is for chain 5")
+
+	// Unsigned legacy transactions, like relayed pre-Bedrock ones, are not checked.
+	unsigned, err := NewTx(&LegacyTx{Gas: 21000, GasPrice: big.NewInt(1), Value: big.NewInt(1), V: new(big.Int), R: new(big.Int), S: new(big.Int)}).MarshalJSON()
+	require.NoError(t, err)
+	require.NoError(t, dec.UnmarshalJSON(withChainID(unsigned, "0x6")))
+}
+
+func TestTransactionUnmarshalJSONLegacyMaxChainID(t *testing.T) {
+	oversized, _ := new(big.Int).SetString("0x8000000000000000000000000000000000000000000000000023", 0)
+	tests := []struct {
+		name          string
+		v             *big.Int
+		maxChainID    uint64
+		expectedError string
+	}{
+		{name: "EIP155", v: big.NewInt(5*2 + 35)},
+		{name: "Homestead27", v: big.NewInt(27)},
+		{name: "Homestead28", v: big.NewInt(28)},
+		{name: "AboveMax", v: big.NewInt(11*2 + 35), maxChainID: 10, expectedError: "invalid chain id 11 in legacy transaction signature, max 10"},
+		{name: "Oversized", v: oversized, expectedError: "invalid chain id 102844034832575377634685573909834406561420991602098741459288064 in legacy transaction signature, max 9223372036854775771"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if test.maxChainID != 0 {
+				defer func(old uint64) { MaxLegacyChainID = old }(MaxLegacyChainID)
+				MaxLegacyChainID = test.maxChainID
+			}
+			enc, err := NewTx(&LegacyTx{Gas: 21000, GasPrice: big.NewInt(1), Value: big.NewInt(1), V: test.v, R: big.NewInt(1), S: big.NewInt(1)}).MarshalJSON()
+			require.NoError(t, err)
+			err = new(Transaction).UnmarshalJSON(enc)
+			if test.expectedError == "" {
+				require.NoError(t, err)
+			} else {
+				require.EqualError(t, err, test.expectedError)
+			}
+		})
+	}
+}
+
+func TestMarshalTxJSONWithSender(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	addr := crypto.PubkeyToAddress(key.PublicKey)
+	signer := NewLondonSigner(big.NewInt(1))
+	tx := MustSignNewTx(key, signer, &DynamicFeeTx{ChainID: big.NewInt(1), Gas: 21000, GasTipCap: big.NewInt(1), GasFeeCap: big.NewInt(1), Value: big.NewInt(1)})
+
+	enc, err := MarshalTxJSONWithSender(tx, signer)
+	require.NoError(t, err)
+	var fields map[string]interface{}
+	require.NoError(t, json.Unmarshal(enc, &fields))
+	require.Equal(t, hexutil.Encode(addr[:]), fields["from"])
+
+	var dec Transaction
+	require.NoError(t, dec.UnmarshalJSON(enc))
+	require.Equal(t, tx.Hash(), dec.Hash())
+
+	// Recovery errors are returned.
+	_, err = MarshalTxJSONWithSender(tx, NewLondonSigner(big.NewInt(2)))
+	require.ErrorIs(t, err, ErrInvalidChainId)
+	_, err = MarshalTxJSONWithSender(tx, nil)
+	require.Error(t, err)
+
+	// Deposits need no signer.
+	from := common.HexToAddress("0x1")
+	deposit := NewTx(&DepositTx{SourceHash: common.HexToHash("0x1234"), From: from, Gas: 1000, Value: big.NewInt(1)})
+	enc, err = MarshalTxJSONWithSender(deposit, nil)
+	require.NoError(t, err)
+	std, err := deposit.MarshalJSON()
+	require.NoError(t, err)
+	require.Equal(t, string(std), string(enc))
+}
+
+func TestMarshalTxJSONWithCachedSender(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	addr := crypto.PubkeyToAddress(key.PublicKey)
+	signer := NewLondonSigner(big.NewInt(1))
+	tx := MustSignNewTx(key, signer, &DynamicFeeTx{ChainID: big.NewInt(1), Gas: 21000, GasTipCap: big.NewInt(1), GasFeeCap: big.NewInt(1), Value: big.NewInt(1)})
+
+	// Without a cached sender, the output is the standard encoding.
+	enc, err := MarshalTxJSONWithCachedSender(tx)
+	require.NoError(t, err)
+	std, err := tx.MarshalJSON()
+	require.NoError(t, err)
+	require.Equal(t, string(std), string(enc))
+	require.Nil(t, tx.from.Load(), "sender recovered")
+
+	_, err = Sender(signer, tx)
+	require.NoError(t, err)
+	enc, err = MarshalTxJSONWithCachedSender(tx)
+	require.NoError(t, err)
+	var fields map[string]interface{}
+	require.NoError(t, json.Unmarshal(enc, &fields))
+	require.Equal(t, hexutil.Encode(addr[:]), fields["from"])
+
+	var dec Transaction
+	require.NoError(t, dec.UnmarshalJSON(enc))
+	require.Equal(t, tx.Hash(), dec.Hash())
+
+	// Deposits always carry their sender.
+	deposit := NewTx(&DepositTx{SourceHash: common.HexToHash("0x1234"), From: common.HexToAddress("0x1"), Gas: 1000, Value: big.NewInt(1)})
+	enc, err = MarshalTxJSONWithCachedSender(deposit)
+	require.NoError(t, err)
+	std, err = deposit.MarshalJSON()
+	require.NoError(t, err)
+	require.Equal(t, string(std), string(enc))
+}
+
+func TestDecodeTransactionAuto(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	signed, err := SignNewTx(key, LatestSignerForChainID(big.NewInt(1)), &DynamicFeeTx{ChainID: big.NewInt(1), Nonce: 1, GasTipCap: big.NewInt(1), GasFeeCap: big.NewInt(2), Gas: 21000, Value: big.NewInt(3)})
+	require.NoError(t, err)
+	legacy, err := SignNewTx(key, HomesteadSigner{}, &LegacyTx{Nonce: 2, GasPrice: big.NewInt(1), Gas: 21000})
+	require.NoError(t, err)
+	to := common.HexToAddress("0x1234")
+	deposit := NewTx(&DepositTx{SourceHash: common.Hash{0xaa}, From: common.HexToAddress("0x01"), To: &to, Mint: big.NewInt(5), Value: big.NewInt(5), Gas: 100_000, Data: []byte{0x01}})
+
+	for _, tx := range []*Transaction{signed, legacy, deposit} {
+		jsonEnc, err := tx.MarshalJSON()
+		require.NoError(t, err)
+		binEnc, err := tx.MarshalBinary()
+		require.NoError(t, err)
+
+		for name, input := range map[string][]byte{
+			"JSON":          jsonEnc,
+			"JSONPadded":    append(append([]byte(" \n"), jsonEnc...), '\n'),
+			"Hex":           []byte(hexutil.Encode(binEnc)),
+			"HexPadded":     []byte(" " + hexutil.Encode(binEnc) + "\n"),
+			"Binary":        binEnc,
+			"BinaryTrailer": append(common.CopyBytes(binEnc), ' '),
+		} {
+			dec, err := DecodeTransactionAuto(input)
+			if name == "BinaryTrailer" {
+				require.Error(t, err, "type %d", tx.Type())
+				continue
+			}
+			require.NoError(t, err, "type %d %s", tx.Type(), name)
+			require.Equal(t, tx.Hash(), dec.Hash(), "type %d %s", tx.Type(), name)
+		}
+	}
+
+	for _, input := range []string{"", " \n", "0x", "0xzz", "{}"} {
+		_, err := DecodeTransactionAuto([]byte(input))
+		require.Error(t, err, "input %q", input)
+	}
+}
+
+func TestUnmarshalJSONPreserving(t *testing.T) {
+	const (
+		input = ` {
+			"type": "0x0", "nonce": "0x1", "gasPrice": "0x1", "gas": "0x5208",
+			"to": null, "value": "0x0", "input": "0x",
+			"v": "0x0", "r": "0x0", "s": "0x0", "extra": [1, 2]
+		}
+	`
+		want = `{"type":"0x0","nonce":"0x1","gasPrice":"0x1","gas":"0x5208","to":null,"value":"0x0","input":"0x","v":"0x0","r":"0x0","s":"0x0","extra":[1,2]}`
+	)
+	tx, raw, err := UnmarshalJSONPreserving([]byte(input))
+	require.NoError(t, err)
+	require.Equal(t, want, string(raw))
+	require.Equal(t, uint64(1), tx.Nonce())
+	require.Equal(t, uint64(21000), tx.Gas())
+
+	var dec Transaction
+	require.NoError(t, dec.UnmarshalJSON([]byte(input)))
+	require.Equal(t, dec.Hash(), tx.Hash())
+
+	_, _, err = UnmarshalJSONPreserving([]byte(`{"type":"0x0"}`))
+	require.Error(t, err)
+}
+
+func TestUnmarshalTransactionsParallel(t *testing.T) {
+	var (
+		txs    = encodingTestTxs(t)
+		inputs [][]byte
+	)
+	for i := 0; i < 200; i++ {
+		enc, err := txs[i%len(txs)].MarshalJSON()
+		require.NoError(t, err)
+		inputs = append(inputs, enc)
+	}
+	for _, workers := range []int{0, 1, 7} {
+		have, err := UnmarshalTransactionsParallel(inputs, workers)
+		require.NoError(t, err)
+		require.Len(t, have, len(inputs))
+		for i, input := range inputs {
+			var want Transaction
+			require.NoError(t, want.UnmarshalJSON(input))
+			require.Equal(t, want.Hash(), have[i].Hash(), "transaction %d", i)
+		}
+	}
+
+	// The error of the lowest failing index is reported.
+	bad := append([][]byte{}, inputs...)
+	bad[150] = []byte(`{"type":"0x0"}`)
+	bad[42] = []byte(`{}`)
+	_, err := UnmarshalTransactionsParallel(bad, 4)
+	require.ErrorContains(t, err, "transaction 42: ")
+}
+
+func TestUnmarshalRawTxObject(t *testing.T) {
+	for _, tx := range encodingTestTxs(t) {
+		if tx.Type() != DynamicFeeTxType && tx.Type() != DepositTxType {
+			continue
+		}
+		t.Run(fmt.Sprintf("type%d", tx.Type()), func(t *testing.T) {
+			bin, err := tx.MarshalBinary()
+			require.NoError(t, err)
+
+			dec, err := UnmarshalRawTxObject([]byte(fmt.Sprintf(`{"raw":"%s"}`, hexutil.Encode(bin))))
+			require.NoError(t, err)
+			require.Equal(t, tx.Hash(), dec.Hash())
+
+			_, err = UnmarshalRawTxObject([]byte(fmt.Sprintf(`{"raw":"%s","nonce":"0x1"}`, hexutil.Encode(bin))))
+			require.EqualError(t, err, "unexpected structured fields next to 'raw' in transaction object")
+		})
+	}
+	_, err := UnmarshalRawTxObject([]byte(`{"nonce":"0x1"}`))
+	require.EqualError(t, err, "missing required field 'raw' in transaction object")
+	_, err = UnmarshalRawTxObject([]byte(`{"raw":"02f8"}`))
+	require.ErrorContains(t, err, "invalid field 'raw'")
+}
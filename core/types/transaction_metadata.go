@@ -0,0 +1,55 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// txWithMetadata is the encoding of a transaction with attached metadata.
+type txWithMetadata struct {
+	Tx   []byte // canonical binary encoding of the transaction
+	Meta []byte // opaque metadata
+}
+
+// EncodeTransactionWithMetadata encodes the transaction together with opaque
+// metadata as the RLP list [tx, meta], where tx is the canonical binary encoding
+// returned by MarshalBinary. The transaction bytes are not altered in any way.
+func EncodeTransactionWithMetadata(tx *Transaction, meta []byte) ([]byte, error) {
+	enc, err := tx.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	return rlp.EncodeToBytes(&txWithMetadata{Tx: enc, Meta: meta})
+}
+
+// DecodeTransactionWithMetadata decodes a transaction and its metadata encoded
+// by EncodeTransactionWithMetadata. Empty metadata is returned as nil.
+func DecodeTransactionWithMetadata(input []byte) (*Transaction, []byte, error) {
+	var dec txWithMetadata
+	if err := rlp.DecodeBytes(input, &dec); err != nil {
+		return nil, nil, err
+	}
+	tx := new(Transaction)
+	if err := tx.UnmarshalBinary(dec.Tx); err != nil {
+		return nil, nil, err
+	}
+	if len(dec.Meta) == 0 {
+		return tx, nil, nil
+	}
+	return tx, dec.Meta, nil
+}
@@ -0,0 +1,65 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+func TestTransactionWithMetadataRoundTrip(t *testing.T) {
+	for i, tx := range encodingTestTxs(t) {
+		canonical, err := tx.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, meta := range [][]byte{nil, []byte("route=queue-7")} {
+			enc, err := EncodeTransactionWithMetadata(tx, meta)
+			if err != nil {
+				t.Fatalf("tx %d: failed to encode: %v", i, err)
+			}
+			// The canonical transaction bytes are embedded unchanged.
+			if !bytes.Contains(enc, canonical) {
+				t.Errorf("tx %d: canonical encoding not embedded", i)
+			}
+			dec, decMeta, err := DecodeTransactionWithMetadata(enc)
+			if err != nil {
+				t.Fatalf("tx %d: failed to decode: %v", i, err)
+			}
+			if dec.Hash() != tx.Hash() {
+				t.Errorf("tx %d: hash mismatch: have %x, want %x", i, dec.Hash(), tx.Hash())
+			}
+			if !bytes.Equal(decMeta, meta) || (meta == nil) != (decMeta == nil) {
+				t.Errorf("tx %d: metadata mismatch: have %q, want %q", i, decMeta, meta)
+			}
+		}
+	}
+}
+
+func TestDecodeTransactionWithMetadataInvalid(t *testing.T) {
+	// Not a list.
+	if _, _, err := DecodeTransactionWithMetadata([]byte{0x80}); err == nil {
+		t.Error("expected error for non-list input")
+	}
+	// Invalid transaction bytes.
+	enc, _ := rlp.EncodeToBytes(&txWithMetadata{Tx: []byte{0x7e, 0xc0}, Meta: []byte{1}})
+	if _, _, err := DecodeTransactionWithMetadata(enc); err == nil {
+		t.Error("expected error for invalid transaction")
+	}
+}
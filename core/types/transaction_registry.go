@@ -0,0 +1,188 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"fmt"
+	"io"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// txTypeCodec holds the functions converting a registered transaction type
+// between its inner representation and its encodings.
+type txTypeCodec struct {
+	factory   func() TxData
+	marshal   func(TxData, *TxJSON)
+	unmarshal func(*TxJSON) (TxData, error)
+}
+
+// txTypeCodecs holds the registered transaction types.
+var txTypeCodecs = make(map[byte]txTypeCodec)
+
+func init() {
+	registerTxType(LegacyTxType, func() TxData { return new(LegacyTx) }, marshalLegacyTxJSON, unmarshalLegacyTxJSON)
+	registerTxType(AccessListTxType, func() TxData { return new(AccessListTx) }, marshalAccessListTxJSON, unmarshalAccessListTxJSON)
+	registerTxType(DynamicFeeTxType, func() TxData { return new(DynamicFeeTx) }, marshalDynamicFeeTxJSON, unmarshalDynamicFeeTxJSON)
+	registerTxType(BlobTxType, func() TxData { return new(BlobTx) }, marshalBlobTxJSON, unmarshalBlobTxJSON)
+	registerTxType(DepositTxType, func() TxData { return new(DepositTx) }, marshalDepositTxJSON, unmarshalDepositTxJSON)
+}
+
+// registerTxType registers a transaction type with the binary and JSON codecs.
+//
+// The factory returns an empty inner transaction, into which the RLP payload of
+// a typed transaction envelope is decoded. If the inner transaction has a
+// validate() error method, it is called after decoding. The marshal function
+// sets the JSON fields of an inner transaction, except for the type and hash.
+// The unmarshal function verifies the JSON fields and creates the inner
+// transaction from them.
+func registerTxType(typ byte, factory func() TxData, marshal func(TxData, *TxJSON), unmarshal func(*TxJSON) (TxData, error)) {
+	if _, ok := txTypeCodecs[typ]; ok {
+		panic(fmt.Sprintf("transaction type %#x already registered", typ))
+	}
+	txTypeCodecs[typ] = txTypeCodec{factory: factory, marshal: marshal, unmarshal: unmarshal}
+}
+
+// RegisterTxType registers a custom EIP-2718 transaction type with the binary
+// and JSON codecs, so that transactions of that type can be decoded with
+// UnmarshalBinary, DecodeRLP and UnmarshalJSON.
+//
+// The factory returns an empty inner transaction, into which the RLP payload of
+// a typed transaction envelope is decoded. If the inner transaction has a
+// Validate() error method, it is called after decoding. The marshal function
+// sets the JSON fields of an inner transaction, except for the type and hash.
+// The unmarshal function verifies the JSON fields and creates the inner
+// transaction from them.
+//
+// RegisterTxType is not safe for concurrent use and should be called from init
+// functions. It panics if the type is already registered, or if it is not a
+// valid EIP-2718 type in the range [0x01, 0x7f].
+func RegisterTxType(typ byte, factory func() CustomTxData, marshal func(CustomTxData, *TxJSON), unmarshal func(*TxJSON) (CustomTxData, error)) {
+	if typ == LegacyTxType || typ > 0x7f {
+		panic(fmt.Sprintf("invalid transaction type %#x", typ))
+	}
+	registerTxType(typ,
+		func() TxData { return &customTxData{factory()} },
+		func(inner TxData, enc *TxJSON) { marshal(inner.(*customTxData).inner, enc) },
+		func(dec *TxJSON) (TxData, error) {
+			inner, err := unmarshal(dec)
+			if err != nil {
+				return nil, err
+			}
+			if inner.TxType() != typ {
+				return nil, fmt.Errorf("custom transaction has type %#x, want %#x", inner.TxType(), typ)
+			}
+			return &customTxData{inner}, nil
+		},
+	)
+}
+
+// CustomTxData is the inner data of a transaction type registered with
+// RegisterTxType. Its RLP encoding is the payload of the typed transaction
+// envelope. The accessors correspond to those of Transaction, and the returned
+// big integers must not be nil. Custom transactions carry no blobs and are
+// never system transactions.
+type CustomTxData interface {
+	TxType() byte       // returns the type ID
+	Copy() CustomTxData // creates a deep copy
+
+	ChainID() *big.Int
+	AccessList() AccessList
+	Data() []byte
+	Gas() uint64
+	GasPrice() *big.Int
+	GasTipCap() *big.Int
+	GasFeeCap() *big.Int
+	Value() *big.Int
+	Nonce() uint64
+	To() *common.Address
+
+	RawSignatureValues() (v, r, s *big.Int)
+	SetSignatureValues(chainID, v, r, s *big.Int)
+
+	// EffectiveGasPrice computes the gas price paid by the transaction, given
+	// the inclusion block baseFee, and stores it in dst, which it returns.
+	EffectiveGasPrice(dst *big.Int, baseFee *big.Int) *big.Int
+}
+
+// NewCustomTx creates a new transaction of a custom type.
+func NewCustomTx(inner CustomTxData) *Transaction {
+	return NewTx(&customTxData{inner})
+}
+
+// CustomTxData returns the inner data of a transaction of a type registered
+// with RegisterTxType. It must not be modified by the caller.
+func (tx *Transaction) CustomTxData() (CustomTxData, bool) {
+	itx, ok := tx.inner.(*customTxData)
+	if !ok {
+		return nil, false
+	}
+	return itx.inner, true
+}
+
+// customTxData adapts a CustomTxData to the TxData interface.
+type customTxData struct {
+	inner CustomTxData
+}
+
+func (tx *customTxData) txType() byte              { return tx.inner.TxType() }
+func (tx *customTxData) copy() TxData              { return &customTxData{tx.inner.Copy()} }
+func (tx *customTxData) chainID() *big.Int         { return tx.inner.ChainID() }
+func (tx *customTxData) accessList() AccessList    { return tx.inner.AccessList() }
+func (tx *customTxData) data() []byte              { return tx.inner.Data() }
+func (tx *customTxData) gas() uint64               { return tx.inner.Gas() }
+func (tx *customTxData) gasPrice() *big.Int        { return tx.inner.GasPrice() }
+func (tx *customTxData) gasTipCap() *big.Int       { return tx.inner.GasTipCap() }
+func (tx *customTxData) gasFeeCap() *big.Int       { return tx.inner.GasFeeCap() }
+func (tx *customTxData) value() *big.Int           { return tx.inner.Value() }
+func (tx *customTxData) nonce() uint64             { return tx.inner.Nonce() }
+func (tx *customTxData) to() *common.Address       { return tx.inner.To() }
+func (tx *customTxData) blobGas() uint64           { return 0 }
+func (tx *customTxData) blobGasFeeCap() *big.Int   { return nil }
+func (tx *customTxData) blobHashes() []common.Hash { return nil }
+func (tx *customTxData) isSystemTx() bool          { return false }
+
+func (tx *customTxData) rawSignatureValues() (v, r, s *big.Int) {
+	return tx.inner.RawSignatureValues()
+}
+
+func (tx *customTxData) setSignatureValues(chainID, v, r, s *big.Int) {
+	tx.inner.SetSignatureValues(chainID, v, r, s)
+}
+
+func (tx *customTxData) effectiveGasPrice(dst *big.Int, baseFee *big.Int) *big.Int {
+	return tx.inner.EffectiveGasPrice(dst, baseFee)
+}
+
+func (tx *customTxData) validate() error {
+	if v, ok := tx.inner.(interface{ Validate() error }); ok {
+		return v.Validate()
+	}
+	return nil
+}
+
+// EncodeRLP implements rlp.Encoder, encoding the custom data as the payload.
+func (tx *customTxData) EncodeRLP(w io.Writer) error {
+	return rlp.Encode(w, tx.inner)
+}
+
+// DecodeRLP implements rlp.Decoder, decoding the payload into the custom data.
+func (tx *customTxData) DecodeRLP(s *rlp.Stream) error {
+	return s.Decode(tx.inner)
+}
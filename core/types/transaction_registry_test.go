@@ -0,0 +1,218 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package types_test
+
+import (
+	"errors"
+	"io"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/stretchr/testify/require"
+)
+
+const customTxType = 0x42
+
+// customTx is a minimal unsigned transaction type, registered from outside the
+// types package.
+type customTx struct {
+	AccountNonce uint64
+	GasLimit     uint64
+	Recipient    *common.Address `rlp:"nil"`
+	Payload      []byte
+}
+
+func (tx *customTx) TxType() byte { return customTxType }
+func (tx *customTx) Copy() types.CustomTxData {
+	cpy := &customTx{AccountNonce: tx.AccountNonce, GasLimit: tx.GasLimit, Payload: common.CopyBytes(tx.Payload)}
+	if tx.Recipient != nil {
+		to := *tx.Recipient
+		cpy.Recipient = &to
+	}
+	return cpy
+}
+func (tx *customTx) ChainID() *big.Int            { return new(big.Int) }
+func (tx *customTx) AccessList() types.AccessList { return nil }
+func (tx *customTx) Data() []byte                 { return tx.Payload }
+func (tx *customTx) Gas() uint64                  { return tx.GasLimit }
+func (tx *customTx) GasPrice() *big.Int           { return new(big.Int) }
+func (tx *customTx) GasTipCap() *big.Int          { return new(big.Int) }
+func (tx *customTx) GasFeeCap() *big.Int          { return new(big.Int) }
+func (tx *customTx) Value() *big.Int              { return new(big.Int) }
+func (tx *customTx) Nonce() uint64                { return tx.AccountNonce }
+func (tx *customTx) To() *common.Address          { return tx.Recipient }
+func (tx *customTx) RawSignatureValues() (v, r, s *big.Int) {
+	return new(big.Int), new(big.Int), new(big.Int)
+}
+func (tx *customTx) SetSignatureValues(chainID, v, r, s *big.Int) {}
+func (tx *customTx) EffectiveGasPrice(dst *big.Int, baseFee *big.Int) *big.Int {
+	return dst.SetUint64(0)
+}
+
+func (tx *customTx) Validate() error {
+	if tx.GasLimit == 0 {
+		return errors.New("custom transaction without gas")
+	}
+	return nil
+}
+
+func marshalCustomTxJSON(inner types.CustomTxData, enc *types.TxJSON) {
+	itx := inner.(*customTx)
+	enc.Nonce = (*hexutil.Uint64)(&itx.AccountNonce)
+	enc.Gas = (*hexutil.Uint64)(&itx.GasLimit)
+	enc.To = itx.Recipient
+	enc.Input = (*hexutil.Bytes)(&itx.Payload)
+}
+
+func unmarshalCustomTxJSON(dec *types.TxJSON) (types.CustomTxData, error) {
+	if dec.Nonce == nil || dec.Gas == nil || dec.Input == nil {
+		return nil, errors.New("missing required field in custom transaction")
+	}
+	return &customTx{AccountNonce: uint64(*dec.Nonce), GasLimit: uint64(*dec.Gas), Recipient: dec.To, Payload: common.CopyBytes(*dec.Input)}, nil
+}
+
+func init() {
+	types.RegisterTxType(customTxType, func() types.CustomTxData { return new(customTx) }, marshalCustomTxJSON, unmarshalCustomTxJSON)
+	types.RegisterTxType(lenientTxType, func() types.CustomTxData { return new(lenientTx) },
+		func(inner types.CustomTxData, enc *types.TxJSON) {
+			marshalCustomTxJSON(&inner.(*lenientTx).customTx, enc)
+		},
+		func(dec *types.TxJSON) (types.CustomTxData, error) {
+			itx, err := unmarshalCustomTxJSON(dec)
+			if err != nil {
+				return nil, err
+			}
+			return &lenientTx{*itx.(*customTx)}, nil
+		})
+}
+
+func TestRegisterTxType(t *testing.T) {
+	to := common.HexToAddress("0x1")
+	tx := types.NewCustomTx(&customTx{AccountNonce: 3, GasLimit: 50000, Recipient: &to, Payload: []byte{0xde, 0xad}})
+	require.Equal(t, uint8(customTxType), tx.Type())
+	require.Equal(t, uint64(3), tx.Nonce())
+	require.Equal(t, types.KindUnknown, tx.Kind())
+	require.Equal(t, []byte{0xde, 0xad}, tx.Data())
+
+	enc, err := tx.MarshalJSON()
+	require.NoError(t, err)
+	require.JSONEq(t, `{"type":"0x42","nonce":"0x3","to":"0x0000000000000000000000000000000000000001","gas":"0xc350","gasPrice":null,"maxPriorityFeePerGas":null,"maxFeePerGas":null,"value":null,"input":"0xdead","v":null,"r":null,"s":null,"hash":"`+tx.Hash().Hex()+`"}`, string(enc))
+
+	var dec types.Transaction
+	require.NoError(t, dec.UnmarshalJSON(enc))
+	require.Equal(t, uint8(customTxType), dec.Type())
+	require.Equal(t, tx.Hash(), dec.Hash())
+
+	bin, err := tx.MarshalBinary()
+	require.NoError(t, err)
+	require.Equal(t, byte(customTxType), bin[0])
+	var decBin types.Transaction
+	require.NoError(t, decBin.UnmarshalBinary(bin))
+	require.Equal(t, tx.Hash(), decBin.Hash())
+	inner, ok := decBin.CustomTxData()
+	require.True(t, ok)
+	require.Equal(t, &customTx{AccountNonce: 3, GasLimit: 50000, Recipient: &to, Payload: []byte{0xde, 0xad}}, inner)
+
+	var decRLP types.Transaction
+	encRLP, err := rlp.EncodeToBytes(tx)
+	require.NoError(t, err)
+	require.NoError(t, rlp.DecodeBytes(encRLP, &decRLP))
+	require.Equal(t, tx.Hash(), decRLP.Hash())
+
+	// Validate is called after binary decoding.
+	invalid, err := types.NewCustomTx(&customTx{AccountNonce: 1}).MarshalBinary()
+	require.NoError(t, err)
+	require.EqualError(t, new(types.Transaction).UnmarshalBinary(invalid), "custom transaction without gas")
+
+	// Built-in transactions have no custom data.
+	_, ok = types.NewTx(&types.LegacyTx{}).CustomTxData()
+	require.False(t, ok)
+}
+
+func TestRegisterTxTypeInvalid(t *testing.T) {
+	factory := func() types.CustomTxData { return new(customTx) }
+	for _, typ := range []byte{types.LegacyTxType, 0x80, 0xff, types.DynamicFeeTxType, types.DepositTxType, customTxType} {
+		require.Panics(t, func() {
+			types.RegisterTxType(typ, factory, marshalCustomTxJSON, unmarshalCustomTxJSON)
+		}, "type %#x", typ)
+	}
+
+	// Unregistered types cannot be decoded.
+	bin, err := types.NewCustomTx(&otherTx{customTx{GasLimit: 1}}).MarshalBinary()
+	require.NoError(t, err)
+	require.ErrorIs(t, new(types.Transaction).UnmarshalBinary(bin), types.ErrTxTypeNotSupported)
+}
+
+// otherTx is a custom transaction type which is never registered.
+type otherTx struct{ customTx }
+
+func (tx *otherTx) TxType() byte { return 0x43 }
+func (tx *otherTx) Copy() types.CustomTxData {
+	return &otherTx{*tx.customTx.Copy().(*customTx)}
+}
+
+const lenientTxType = 0x44
+
+// lenientTx is a custom transaction type whose decoder ignores trailing fields,
+// e.g. to accept encodings produced by a later version of the type.
+type lenientTx struct{ customTx }
+
+func (tx *lenientTx) TxType() byte { return lenientTxType }
+func (tx *lenientTx) Copy() types.CustomTxData {
+	return &lenientTx{*tx.customTx.Copy().(*customTx)}
+}
+
+func (tx *lenientTx) EncodeRLP(w io.Writer) error { return rlp.Encode(w, &tx.customTx) }
+
+func (tx *lenientTx) DecodeRLP(s *rlp.Stream) error {
+	var dec struct {
+		AccountNonce uint64
+		GasLimit     uint64
+		Recipient    *common.Address `rlp:"nil"`
+		Payload      []byte
+		Rest         []rlp.RawValue `rlp:"tail"`
+	}
+	if err := s.Decode(&dec); err != nil {
+		return err
+	}
+	tx.customTx = customTx{AccountNonce: dec.AccountNonce, GasLimit: dec.GasLimit, Recipient: dec.Recipient, Payload: dec.Payload}
+	return nil
+}
+
+func TestUnmarshalBinaryStrictRegisteredType(t *testing.T) {
+	to := common.HexToAddress("0x1")
+	canonical, err := types.NewCustomTx(&lenientTx{customTx{AccountNonce: 3, GasLimit: 50000, Recipient: &to}}).MarshalBinary()
+	require.NoError(t, err)
+	require.NoError(t, new(types.Transaction).UnmarshalBinaryStrict(canonical))
+
+	// An encoding with an extra trailing field is accepted by the type's
+	// decoder, but does not round-trip.
+	payload, err := rlp.EncodeToBytes([]interface{}{uint64(3), uint64(50000), to, []byte{}, uint64(1)})
+	require.NoError(t, err)
+	extended := append([]byte{lenientTxType}, payload...)
+
+	var tx types.Transaction
+	require.NoError(t, tx.UnmarshalBinary(extended))
+	enc, err := tx.MarshalBinary()
+	require.NoError(t, err)
+	require.Equal(t, canonical, enc)
+	require.ErrorIs(t, new(types.Transaction).UnmarshalBinaryStrict(extended), types.ErrTxNonCanonical)
+}
@@ -17,6 +17,7 @@
 package types
 
 import (
+	"bytes"
 	"crypto/ecdsa"
 	"errors"
 	"fmt"
@@ -25,10 +26,15 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
 )
 
 var ErrInvalidChainId = errors.New("invalid chain id for signer")
 
+// ErrUnprotectedSignature is returned by VerifyEIP155ChainID for signatures
+// which do not commit to a chain ID.
+var ErrUnprotectedSignature = errors.New("transaction signature is not EIP-155 protected")
+
 // sigCache is used to cache the derived sender and contains
 // the signer used to derive it.
 type sigCache struct {
@@ -126,6 +132,84 @@ func MustSignNewTx(prv *ecdsa.PrivateKey, s Signer, txdata TxData) *Transaction
 	return tx
 }
 
+// VerifyEIP155ChainID checks that a signed legacy transaction has an EIP-155
+// signature for the given chain, i.e. that its V value is chainID*2+35+{0,1}.
+//
+// Homestead signatures with V in {27, 28} sign a hash that does not include the
+// chain ID and are rejected with ErrUnprotectedSignature. They cannot be
+// converted to EIP-155 form, as that would change the recovered sender.
+func (tx *Transaction) VerifyEIP155ChainID(chainID *big.Int) error {
+	if tx.Type() != LegacyTxType {
+		return ErrTxTypeNotSupported
+	}
+	if chainID == nil || chainID.Sign() <= 0 {
+		return fmt.Errorf("%w: %v", ErrInvalidChainId, chainID)
+	}
+	v, _, _ := tx.RawSignatureValues()
+	if v == nil || !tx.Protected() {
+		return ErrUnprotectedSignature
+	}
+	// A matching chain ID implies that V is chainID*2+35 or chainID*2+36.
+	if txChainID := deriveChainId(v); txChainID.Cmp(chainID) != 0 {
+		return fmt.Errorf("%w: have %d want %d", ErrInvalidChainId, txChainID, chainID)
+	}
+	return nil
+}
+
+// EncodeRLPForSigning returns the payload whose keccak256 hash is signed for the
+// transaction on the given chain. For typed transactions, this is the type byte
+// followed by the RLP list of the signed fields. Legacy transactions use the
+// EIP-155 payload, or the Homestead payload if chainID is nil or zero.
+// Deposit transactions are never signed and return ErrTxTypeNotSupported.
+func (tx *Transaction) EncodeRLPForSigning(chainID *big.Int) ([]byte, error) {
+	if tx.Type() == LegacyTxType {
+		if chainID != nil && chainID.Sign() == 0 {
+			chainID = nil
+		}
+		return rlp.EncodeToBytes(legacySigningFields(tx, chainID))
+	}
+	if chainID == nil {
+		return nil, errors.New("chain ID required to sign typed transaction")
+	}
+	fields := signingFields(tx, chainID)
+	if fields == nil {
+		return nil, ErrTxTypeNotSupported
+	}
+	var buf bytes.Buffer
+	buf.WriteByte(tx.Type())
+	if err := rlp.Encode(&buf, fields); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// signingFields returns the fields of a transaction which are signed for the
+// given chain, or nil if the transaction type cannot be signed. The signers'
+// Hash methods and EncodeRLPForSigning share these lists.
+func signingFields(tx *Transaction, chainID *big.Int) []interface{} {
+	switch tx.Type() {
+	case LegacyTxType:
+		return legacySigningFields(tx, chainID)
+	case AccessListTxType:
+		return []interface{}{chainID, tx.Nonce(), tx.GasPrice(), tx.Gas(), tx.To(), tx.Value(), tx.Data(), tx.AccessList()}
+	case DynamicFeeTxType:
+		return []interface{}{chainID, tx.Nonce(), tx.GasTipCap(), tx.GasFeeCap(), tx.Gas(), tx.To(), tx.Value(), tx.Data(), tx.AccessList()}
+	case BlobTxType:
+		return []interface{}{chainID, tx.Nonce(), tx.GasTipCap(), tx.GasFeeCap(), tx.Gas(), tx.To(), tx.Value(), tx.Data(), tx.AccessList(), tx.BlobGasFeeCap(), tx.BlobHashes()}
+	}
+	return nil
+}
+
+// legacySigningFields returns the signed fields of a legacy transaction. The
+// EIP-155 fields are appended unless chainID is nil.
+func legacySigningFields(tx *Transaction, chainID *big.Int) []interface{} {
+	fields := []interface{}{tx.Nonce(), tx.GasPrice(), tx.Gas(), tx.To(), tx.Value(), tx.Data()}
+	if chainID != nil {
+		fields = append(fields, chainID, uint(0), uint(0))
+	}
+	return fields
+}
+
 // Sender returns the address derived from the signature (V, R, S) using secp256k1
 // elliptic curve and an error if it failed deriving or upon an incorrect
 // signature.
@@ -133,13 +217,19 @@ func MustSignNewTx(prv *ecdsa.PrivateKey, s Signer, txdata TxData) *Transaction
 // Sender may cache the address, allowing it to be used regardless of
 // signing method. The cache is invalidated if the cached signer does
 // not match the signer used in the current call.
+//
+// Deposit transactions are not signed. Their sender is the address they carry,
+// which is returned for any signer.
 func Sender(signer Signer, tx *Transaction) (common.Address, error) {
+	if dep, ok := tx.depositTx(); ok {
+		return dep.From, nil
+	}
 	if sc := tx.from.Load(); sc != nil {
 		sigCache := sc.(sigCache)
 		// If the signer used to derive from in a previous
 		// call is not the same as used current, invalidate
 		// the cache.
-		if sigCache.signer.Equal(signer) {
+		if sigCache.signer != nil && sigCache.signer.Equal(signer) {
 			return sigCache.from, nil
 		}
 	}
@@ -227,21 +317,7 @@ func (s cancunSigner) Hash(tx *Transaction) common.Hash {
 	if tx.Type() != BlobTxType {
 		return s.londonSigner.Hash(tx)
 	}
-	return prefixedRlpHash(
-		tx.Type(),
-		[]interface{}{
-			s.chainId,
-			tx.Nonce(),
-			tx.GasTipCap(),
-			tx.GasFeeCap(),
-			tx.Gas(),
-			tx.To(),
-			tx.Value(),
-			tx.Data(),
-			tx.AccessList(),
-			tx.BlobGasFeeCap(),
-			tx.BlobHashes(),
-		})
+	return prefixedRlpHash(tx.Type(), signingFields(tx, s.chainId))
 }
 
 type londonSigner struct{ eip2930Signer }
@@ -309,19 +385,7 @@ func (s londonSigner) Hash(tx *Transaction) common.Hash {
 	if tx.Type() != DynamicFeeTxType {
 		return s.eip2930Signer.Hash(tx)
 	}
-	return prefixedRlpHash(
-		tx.Type(),
-		[]interface{}{
-			s.chainId,
-			tx.Nonce(),
-			tx.GasTipCap(),
-			tx.GasFeeCap(),
-			tx.Gas(),
-			tx.To(),
-			tx.Value(),
-			tx.Data(),
-			tx.AccessList(),
-		})
+	return prefixedRlpHash(tx.Type(), signingFields(tx, s.chainId))
 }
 
 type eip2930Signer struct{ EIP155Signer }
@@ -386,28 +450,9 @@ func (s eip2930Signer) SignatureValues(tx *Transaction, sig []byte) (R, S, V *bi
 func (s eip2930Signer) Hash(tx *Transaction) common.Hash {
 	switch tx.Type() {
 	case LegacyTxType:
-		return rlpHash([]interface{}{
-			tx.Nonce(),
-			tx.GasPrice(),
-			tx.Gas(),
-			tx.To(),
-			tx.Value(),
-			tx.Data(),
-			s.chainId, uint(0), uint(0),
-		})
+		return rlpHash(legacySigningFields(tx, s.chainId))
 	case AccessListTxType:
-		return prefixedRlpHash(
-			tx.Type(),
-			[]interface{}{
-				s.chainId,
-				tx.Nonce(),
-				tx.GasPrice(),
-				tx.Gas(),
-				tx.To(),
-				tx.Value(),
-				tx.Data(),
-				tx.AccessList(),
-			})
+		return prefixedRlpHash(tx.Type(), signingFields(tx, s.chainId))
 	default:
 		// This _should_ not happen, but in case someone sends in a bad
 		// json struct via RPC, it's probably more prudent to return an
@@ -477,15 +522,7 @@ func (s EIP155Signer) SignatureValues(tx *Transaction, sig []byte) (R, S, V *big
 // Hash returns the hash to be signed by the sender.
 // It does not uniquely identify the transaction.
 func (s EIP155Signer) Hash(tx *Transaction) common.Hash {
-	return rlpHash([]interface{}{
-		tx.Nonce(),
-		tx.GasPrice(),
-		tx.Gas(),
-		tx.To(),
-		tx.Value(),
-		tx.Data(),
-		s.chainId, uint(0), uint(0),
-	})
+	return rlpHash(legacySigningFields(tx, s.chainId))
 }
 
 // HomesteadSigner implements Signer interface using the
@@ -549,14 +586,7 @@ func (fs FrontierSigner) SignatureValues(tx *Transaction, sig []byte) (r, s, v *
 // Hash returns the hash to be signed by the sender.
 // It does not uniquely identify the transaction.
 func (fs FrontierSigner) Hash(tx *Transaction) common.Hash {
-	return rlpHash([]interface{}{
-		tx.Nonce(),
-		tx.GasPrice(),
-		tx.Gas(),
-		tx.To(),
-		tx.Value(),
-		tx.Data(),
-	})
+	return rlpHash(legacySigningFields(tx, nil))
 }
 
 func decodeSignature(sig []byte) (r, s, v *big.Int) {
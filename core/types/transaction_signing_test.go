@@ -17,6 +17,7 @@
 package types
 
 import (
+	"bytes"
 	"errors"
 	"math/big"
 	"testing"
@@ -136,3 +137,181 @@ func TestChainId(t *testing.T) {
 		t.Error("expected no error")
 	}
 }
+
+func TestEncodeRLPForSigning(t *testing.T) {
+	// Signing payload example from EIP-155.
+	to := common.HexToAddress("0x3535353535353535353535353535353535353535")
+	tx := NewTransaction(9, to, big.NewInt(1e18), 21000, big.NewInt(20e9), nil)
+	have, err := tx.EncodeRLPForSigning(big.NewInt(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := common.FromHex("ec098504a817c800825208943535353535353535353535353535353535353535880de0b6b3a764000080018080")
+	if !bytes.Equal(have, want) {
+		t.Errorf("wrong EIP-155 signing payload: have %x, want %x", have, want)
+	}
+	if hash := crypto.Keccak256Hash(have); hash != common.HexToHash("0xdaf5a779ae972f972197303d7b574746c7ef83eadac0f2791ad23db92e4c8e53") {
+		t.Errorf("wrong EIP-155 signing hash: %x", hash)
+	}
+
+	// Unprotected legacy transactions.
+	if have, err = tx.EncodeRLPForSigning(nil); err != nil {
+		t.Fatal(err)
+	}
+	if hash := crypto.Keccak256Hash(have); hash != (HomesteadSigner{}).Hash(tx) {
+		t.Errorf("wrong Homestead signing hash: have %x, want %x", hash, HomesteadSigner{}.Hash(tx))
+	}
+
+	// Typed transactions must hash to the signer's hash.
+	var (
+		chainID = big.NewInt(123)
+		signer  = NewCancunSigner(chainID)
+	)
+	for _, tx := range encodingTestTxs(t) {
+		have, err := tx.EncodeRLPForSigning(chainID)
+		if tx.Type() == DepositTxType {
+			if !errors.Is(err, ErrTxTypeNotSupported) {
+				t.Errorf("wrong error for deposit tx: %v", err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("tx type %d: %v", tx.Type(), err)
+		}
+		if hash := crypto.Keccak256Hash(have); hash != signer.Hash(tx) {
+			t.Errorf("tx type %d: wrong signing hash: have %x, want %x", tx.Type(), hash, signer.Hash(tx))
+		}
+		if tx.Type() != LegacyTxType && have[0] != tx.Type() {
+			t.Errorf("tx type %d: wrong payload prefix %#x", tx.Type(), have[0])
+		}
+	}
+
+	// Every signer which supports a transaction type must agree with the payload.
+	signers := map[byte][]Signer{
+		LegacyTxType:     {NewEIP155Signer(chainID), NewEIP2930Signer(chainID), NewLondonSigner(chainID), signer},
+		AccessListTxType: {NewEIP2930Signer(chainID), NewLondonSigner(chainID), signer},
+		DynamicFeeTxType: {NewLondonSigner(chainID), signer},
+		BlobTxType:       {signer},
+	}
+	for _, tx := range encodingTestTxs(t) {
+		for _, s := range signers[tx.Type()] {
+			payload, err := tx.EncodeRLPForSigning(s.ChainID())
+			if err != nil {
+				t.Fatalf("tx type %d: %v", tx.Type(), err)
+			}
+			if hash := crypto.Keccak256Hash(payload); hash != s.Hash(tx) {
+				t.Errorf("tx type %d, signer %T: wrong signing hash: have %x, want %x", tx.Type(), s, hash, s.Hash(tx))
+			}
+		}
+	}
+	legacy := encodingTestTxs(t)[0]
+	payload, err := legacy.EncodeRLPForSigning(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []Signer{HomesteadSigner{}, FrontierSigner{}} {
+		if hash := crypto.Keccak256Hash(payload); hash != s.Hash(legacy) {
+			t.Errorf("signer %T: wrong signing hash: have %x, want %x", s, hash, s.Hash(legacy))
+		}
+	}
+}
+
+func TestVerifyEIP155ChainID(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	addr := crypto.PubkeyToAddress(key.PublicKey)
+	chainID := big.NewInt(18)
+
+	tx, err := SignTx(NewTransaction(0, addr, new(big.Int), 21000, big.NewInt(1), nil), NewEIP155Signer(chainID), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.VerifyEIP155ChainID(chainID); err != nil {
+		t.Errorf("EIP-155 tx rejected: %v", err)
+	}
+	if err := tx.VerifyEIP155ChainID(big.NewInt(19)); !errors.Is(err, ErrInvalidChainId) {
+		t.Errorf("wrong error for mismatching chain ID: %v", err)
+	}
+
+	// Pre-EIP-155 transactions do not commit to a chain.
+	tx, err = SignTx(NewTransaction(0, addr, new(big.Int), 21000, big.NewInt(1), nil), HomesteadSigner{}, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.VerifyEIP155ChainID(chainID); !errors.Is(err, ErrUnprotectedSignature) {
+		t.Errorf("wrong error for unprotected tx: %v", err)
+	}
+
+	// Typed transactions carry their chain ID separately.
+	dyn := NewTx(&DynamicFeeTx{ChainID: chainID, GasTipCap: big.NewInt(1), GasFeeCap: big.NewInt(1), Gas: 21000})
+	if err := dyn.VerifyEIP155ChainID(chainID); !errors.Is(err, ErrTxTypeNotSupported) {
+		t.Errorf("wrong error for typed tx: %v", err)
+	}
+}
+
+func TestRawSignatureValues(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	chainID := big.NewInt(18)
+	signer := NewLondonSigner(chainID)
+
+	// Legacy transactions return the raw EIP-155 V value.
+	legacy, err := SignNewTx(key, signer, &LegacyTx{Gas: 21000, GasPrice: big.NewInt(1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, r, s := legacy.RawSignatureValues()
+	if v.Cmp(big.NewInt(2*18+35)) != 0 && v.Cmp(big.NewInt(2*18+36)) != 0 {
+		t.Errorf("legacy: wrong V %d", v)
+	}
+	if r.Sign() == 0 || s.Sign() == 0 {
+		t.Errorf("legacy: missing R or S")
+	}
+
+	// Typed transactions return the y-parity of the signature.
+	dyn, err := SignNewTx(key, signer, &DynamicFeeTx{ChainID: chainID, Gas: 21000, GasTipCap: big.NewInt(1), GasFeeCap: big.NewInt(1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, r, s = dyn.RawSignatureValues()
+	if v.Cmp(common.Big1) > 0 {
+		t.Errorf("dynamic fee: V %d is not a y-parity", v)
+	}
+	hash := signer.Hash(dyn)
+	sig := make([]byte, crypto.SignatureLength)
+	r.FillBytes(sig[:32])
+	s.FillBytes(sig[32:64])
+	sig[64] = byte(v.Uint64())
+	pub, err := crypto.SigToPub(hash[:], sig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if crypto.PubkeyToAddress(*pub) != crypto.PubkeyToAddress(key.PublicKey) {
+		t.Errorf("dynamic fee: signature values do not recover the signer")
+	}
+}
+
+func TestDepositSender(t *testing.T) {
+	from := common.HexToAddress("0x01")
+	deposit := NewTx(&DepositTx{SourceHash: common.HexToHash("0x1234"), From: from, Gas: 1000, Value: big.NewInt(1)})
+	var withNonce Transaction
+	if err := withNonce.UnmarshalJSON([]byte(`{"type":"0x7e","nonce":"0x5","to":null,"gas":"0x3e8","value":"0x1","input":"0x","sourceHash":"0x0000000000000000000000000000000000000000000000000000000000001234","from":"0x0000000000000000000000000000000000000001"}`)); err != nil {
+		t.Fatal(err)
+	}
+	signers := []Signer{
+		FrontierSigner{},
+		HomesteadSigner{},
+		NewEIP155Signer(big.NewInt(1)),
+		NewEIP2930Signer(big.NewInt(1)),
+		NewLondonSigner(big.NewInt(1)),
+		NewCancunSigner(big.NewInt(1)),
+	}
+	for _, signer := range signers {
+		for _, tx := range []*Transaction{deposit, &withNonce} {
+			have, err := Sender(signer, tx)
+			if err != nil {
+				t.Errorf("%T: %v", signer, err)
+			} else if have != from {
+				t.Errorf("%T: sender mismatch: have %x, want %x", signer, have, from)
+			}
+		}
+	}
+}
@@ -18,6 +18,7 @@ package types
 
 import (
 	"bytes"
+	"compress/gzip"
 	"crypto/ecdsa"
 	"encoding/json"
 	"errors"
@@ -25,12 +26,17 @@ import (
 	"math/big"
 	"math/rand"
 	"reflect"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/crypto/kzg4844"
+	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/holiman/uint256"
 )
 
 // The values in those tests are from the Transaction Tests
@@ -599,3 +605,939 @@ func TestTransactionSizes(t *testing.T) {
 		}
 	}
 }
+
+// encodingTestTxs returns a set of transactions covering all transaction types.
+func encodingTestTxs(t testing.TB) []*Transaction {
+	key, _ := crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	signer := NewCancunSigner(big.NewInt(123))
+	to := common.HexToAddress("0x01")
+	var txs []*Transaction
+	for i, txdata := range []TxData{
+		&LegacyTx{Nonce: 1, GasPrice: big.NewInt(500), Gas: 1000000, To: &to, Value: big.NewInt(1), Data: []byte("abcdef")},
+		&AccessListTx{ChainID: big.NewInt(123), Nonce: 1, GasPrice: big.NewInt(500), Gas: 1000000, To: &to, Value: big.NewInt(1),
+			AccessList: AccessList{{Address: to, StorageKeys: []common.Hash{{0x01}}}}},
+		&DynamicFeeTx{ChainID: big.NewInt(123), Nonce: 1, Gas: 1000000, To: &to, Value: big.NewInt(1), GasTipCap: big.NewInt(500), GasFeeCap: big.NewInt(500)},
+		&BlobTx{ChainID: uint256.NewInt(123), Nonce: 1, Gas: 1000000, To: &to, Value: uint256.NewInt(1), GasTipCap: uint256.NewInt(500),
+			GasFeeCap: uint256.NewInt(500), BlobFeeCap: uint256.NewInt(1), BlobHashes: []common.Hash{{0x01}}},
+	} {
+		tx, err := SignNewTx(key, signer, txdata)
+		if err != nil {
+			t.Fatalf("test %d: %v", i, err)
+		}
+		txs = append(txs, tx)
+	}
+	txs = append(txs, NewTx(&DepositTx{
+		SourceHash: common.HexToHash("0x1234"),
+		From:       to,
+		To:         &to,
+		Mint:       big.NewInt(34),
+		Value:      big.NewInt(1),
+		Gas:        1000000,
+		Data:       []byte("abcdef"),
+	}))
+	return txs
+}
+
+func TestTransactionEncodeInto(t *testing.T) {
+	var buf bytes.Buffer
+	for i, tx := range encodingTestTxs(t) {
+		want, err := tx.MarshalBinary()
+		if err != nil {
+			t.Fatalf("test %d: failed to marshal tx: %v", i, err)
+		}
+		buf.Reset()
+		if err := tx.EncodeInto(&buf); err != nil {
+			t.Fatalf("test %d: failed to encode tx: %v", i, err)
+		}
+		if !bytes.Equal(buf.Bytes(), want) {
+			t.Errorf("test %d: encoding mismatch, have %x want %x", i, buf.Bytes(), want)
+		}
+	}
+}
+
+func BenchmarkTransactionMarshalBinary(b *testing.B) {
+	txs := encodingTestTxs(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := txs[i%len(txs)].MarshalBinary(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkTransactionEncodeInto(b *testing.B) {
+	var (
+		txs = encodingTestTxs(b)
+		buf bytes.Buffer
+	)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		buf.Reset()
+		if err := txs[i%len(txs)].EncodeInto(&buf); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestTransactionValidate(t *testing.T) {
+	key, _ := crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	signer := NewCancunSigner(big.NewInt(123))
+	sign := func(txdata TxData) *Transaction {
+		tx, err := SignNewTx(key, signer, txdata)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tx
+	}
+	// highS flips the s value of a typed transaction's signature into the upper
+	// half of the curve order, which is not a canonical signature.
+	highS := func(tx *Transaction) *Transaction {
+		cpy := tx.inner.copy()
+		v, r, s := cpy.rawSignatureValues()
+		cpy.setSignatureValues(tx.ChainId(), v, r, new(big.Int).Sub(crypto.S256().Params().N, s))
+		return NewTx(cpy)
+	}
+	to := common.HexToAddress("0x01")
+
+	tests := []struct {
+		name  string
+		tx    *Transaction
+		valid bool
+	}{
+		{"legacy", sign(&LegacyTx{Nonce: 1, GasPrice: big.NewInt(1), Gas: 21000, To: &to}), true},
+		{"legacy, invalid r", NewTx(&LegacyTx{Nonce: 1, GasPrice: big.NewInt(1), Gas: 21000, To: &to, V: big.NewInt(27), R: big.NewInt(0), S: big.NewInt(1)}), false},
+		{"access list", sign(&AccessListTx{ChainID: big.NewInt(123), GasPrice: big.NewInt(1), Gas: 21000, To: &to}), true},
+		{"access list, high s", highS(sign(&AccessListTx{ChainID: big.NewInt(123), GasPrice: big.NewInt(1), Gas: 21000, To: &to})), false},
+		{"dynamic fee", sign(&DynamicFeeTx{ChainID: big.NewInt(123), GasTipCap: big.NewInt(1), GasFeeCap: big.NewInt(1), Gas: 21000, To: &to}), true},
+		{"dynamic fee, invalid v", NewTx(&DynamicFeeTx{ChainID: big.NewInt(123), GasTipCap: big.NewInt(1), GasFeeCap: big.NewInt(1), Gas: 21000, To: &to, V: big.NewInt(27), R: big.NewInt(1), S: big.NewInt(1)}), false},
+		{"blob", sign(&BlobTx{ChainID: uint256.NewInt(123), BlobHashes: []common.Hash{{0x01}}}), true},
+		{"blob, invalid hash version", NewTx(&BlobTx{BlobHashes: []common.Hash{{0x02}}}), false},
+		{"deposit", NewTx(&DepositTx{SourceHash: common.Hash{0x01}, From: to}), true},
+		{"deposit, zero source hash", NewTx(&DepositTx{From: to}), false},
+	}
+	for _, test := range tests {
+		err := test.tx.Validate()
+		if test.valid && err != nil {
+			t.Errorf("%s: unexpected error: %v", test.name, err)
+		}
+		if !test.valid && err == nil {
+			t.Errorf("%s: expected error", test.name)
+		}
+	}
+}
+
+func TestCompareForBlockOrder(t *testing.T) {
+	var (
+		baseFee = big.NewInt(10)
+		deposit = NewTx(&DepositTx{SourceHash: common.Hash{0x01}})
+		legacy  = NewTx(&LegacyTx{GasPrice: big.NewInt(100)})
+		// Effective gas price 10+5=15.
+		dynamicLow = NewTx(&DynamicFeeTx{GasTipCap: big.NewInt(5), GasFeeCap: big.NewInt(50)})
+		// Effective gas price capped at 20.
+		dynamicHigh = NewTx(&DynamicFeeTx{GasTipCap: big.NewInt(50), GasFeeCap: big.NewInt(20)})
+	)
+	tests := []struct {
+		name string
+		a, b *Transaction
+		want int
+	}{
+		{"deposit before legacy", deposit, legacy, -1},
+		{"legacy after deposit", legacy, deposit, 1},
+		{"deposit before dynamic fee", deposit, dynamicHigh, -1},
+		{"deposits unordered", deposit, deposit, 0},
+		{"higher effective price first", dynamicHigh, dynamicLow, -1},
+		{"lower effective price last", dynamicLow, dynamicHigh, 1},
+		{"legacy before cheaper dynamic fee", legacy, dynamicHigh, -1},
+	}
+	for _, test := range tests {
+		if have := CompareForBlockOrder(test.a, test.b, baseFee); have != test.want {
+			t.Errorf("%s: have %d, want %d", test.name, have, test.want)
+		}
+	}
+}
+
+// TestTransactionGasFeeCap checks that GasFeeCap reads the fee uniformly across
+// transaction types: the gas price for pre-London transactions, the fee cap for
+// dynamic fee ones and zero for deposits.
+func TestTransactionGasFeeCap(t *testing.T) {
+	tests := []struct {
+		tx   *Transaction
+		want int64
+	}{
+		{NewTx(&LegacyTx{GasPrice: big.NewInt(1)}), 1},
+		{NewTx(&AccessListTx{GasPrice: big.NewInt(2)}), 2},
+		{NewTx(&DynamicFeeTx{GasTipCap: big.NewInt(1), GasFeeCap: big.NewInt(3)}), 3},
+		{NewTx(&BlobTx{GasTipCap: uint256.NewInt(1), GasFeeCap: uint256.NewInt(4)}), 4},
+		{NewTx(&DepositTx{}), 0},
+	}
+	for _, test := range tests {
+		if have := test.tx.GasFeeCap(); have.Cmp(big.NewInt(test.want)) != 0 {
+			t.Errorf("type %d: have %v, want %d", test.tx.Type(), have, test.want)
+		}
+	}
+}
+
+func TestDecodeTransactionsRLP(t *testing.T) {
+	txs := encodingTestTxs(t)
+	enc, err := rlp.EncodeToBytes(Transactions(txs))
+	if err != nil {
+		t.Fatal(err)
+	}
+	have, err := DecodeTransactionsRLP(rlp.NewStream(bytes.NewReader(enc), 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(have) != len(txs) {
+		t.Fatalf("wrong number of transactions: have %d, want %d", len(have), len(txs))
+	}
+	for i := range txs {
+		if have[i].Type() != txs[i].Type() {
+			t.Errorf("tx %d: wrong type: have %d, want %d", i, have[i].Type(), txs[i].Type())
+		}
+		if have[i].Hash() != txs[i].Hash() {
+			t.Errorf("tx %d: wrong hash: have %x, want %x", i, have[i].Hash(), txs[i].Hash())
+		}
+	}
+
+	// Replace the third element with a corrupted typed envelope.
+	elems := make([]rlp.RawValue, len(txs))
+	for i, tx := range txs {
+		if elems[i], err = rlp.EncodeToBytes(tx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	elems[2], _ = rlp.EncodeToBytes([]byte{DynamicFeeTxType, 0xc1, 0xff})
+	if enc, err = rlp.EncodeToBytes(elems); err != nil {
+		t.Fatal(err)
+	}
+	_, err = DecodeTransactionsRLP(rlp.NewStream(bytes.NewReader(enc), 0))
+	if err == nil || !strings.HasPrefix(err.Error(), "transaction 2: ") {
+		t.Fatalf("wrong error for corrupted element: %v", err)
+	}
+}
+
+func TestDecodeTransactionRLP(t *testing.T) {
+	for i, tx := range encodingTestTxs(t) {
+		enc, err := rlp.EncodeToBytes(tx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		have, err := DecodeTransactionRLP(rlp.NewStream(bytes.NewReader(enc), 0))
+		if err != nil {
+			t.Fatalf("tx %d: %v", i, err)
+		}
+		if have.Hash() != tx.Hash() {
+			t.Errorf("tx %d: wrong hash: have %x, want %x", i, have.Hash(), tx.Hash())
+		}
+	}
+}
+
+func TestTransactionKind(t *testing.T) {
+	want := []TxKind{KindLegacy, KindAccessList, KindDynamicFee, KindBlob, KindDeposit}
+	for i, tx := range encodingTestTxs(t) {
+		if kind := tx.Kind(); kind != want[i] {
+			t.Errorf("tx type %d: wrong kind %d, want %d", tx.Type(), kind, want[i])
+		}
+	}
+}
+
+func TestTransactionBlobGas(t *testing.T) {
+	for _, tx := range encodingTestTxs(t) {
+		switch tx.Type() {
+		case BlobTxType:
+			if want := params.BlobTxDataGasPerBlob * uint64(len(tx.BlobHashes())); tx.BlobGas() != want {
+				t.Errorf("blob tx: wrong blob gas %d, want %d", tx.BlobGas(), want)
+			}
+			if tx.BlobGas() == 0 {
+				t.Errorf("blob tx: zero blob gas")
+			}
+			if tx.BlobGasFeeCap() == nil || tx.BlobGasFeeCap().Cmp(common.Big1) != 0 {
+				t.Errorf("blob tx: wrong blob gas fee cap %v", tx.BlobGasFeeCap())
+			}
+		default:
+			if tx.BlobGas() != 0 {
+				t.Errorf("tx type %d: non-zero blob gas %d", tx.Type(), tx.BlobGas())
+			}
+			if tx.BlobGasFeeCap() != nil {
+				t.Errorf("tx type %d: non-nil blob gas fee cap %v", tx.Type(), tx.BlobGasFeeCap())
+			}
+		}
+	}
+}
+
+func TestTransactionHasTypePrefix(t *testing.T) {
+	for _, tx := range encodingTestTxs(t) {
+		enc, err := tx.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := tx.Type() != LegacyTxType; tx.HasTypePrefix() != want {
+			t.Errorf("tx type %d: HasTypePrefix = %v, want %v", tx.Type(), tx.HasTypePrefix(), want)
+		}
+		// Typed envelopes start with the type byte, legacy ones with an RLP list header.
+		if prefixed := enc[0] <= 0x7f; prefixed != tx.HasTypePrefix() {
+			t.Errorf("tx type %d: encoding starts with %#x", tx.Type(), enc[0])
+		}
+	}
+}
+
+func TestTransactionRecomputeHash(t *testing.T) {
+	txs := encodingTestTxs(t)
+	for i := 1; i < len(txs); i++ {
+		prev, next := txs[i-1], txs[i]
+		// Decoding into a transaction with a cached hash leaves the cache stale.
+		tx := new(Transaction)
+		if err := tx.UnmarshalBinary(mustMarshalBinary(t, prev)); err != nil {
+			t.Fatal(err)
+		}
+		tx.Hash()
+		if err := tx.UnmarshalBinary(mustMarshalBinary(t, next)); err != nil {
+			t.Fatal(err)
+		}
+		if tx.Hash() != prev.Hash() {
+			t.Fatalf("tx %d: expected stale cached hash", i)
+		}
+		if have, want := tx.RecomputeHash(), next.Hash(); have != want {
+			t.Errorf("tx %d: recomputed hash %x, want %x", i, have, want)
+		}
+		if tx.Hash() != next.Hash() {
+			t.Errorf("tx %d: hash not cached after recompute", i)
+		}
+		if tx.Size() != next.Size() {
+			t.Errorf("tx %d: size %d, want %d", i, tx.Size(), next.Size())
+		}
+	}
+}
+
+func TestTransactionRecomputeHashSender(t *testing.T) {
+	signer := NewLondonSigner(big.NewInt(1))
+	key1, _ := crypto.GenerateKey()
+	key2, _ := crypto.GenerateKey()
+	to := common.HexToAddress("0x01")
+	txdata := &DynamicFeeTx{ChainID: big.NewInt(1), Nonce: 1, Gas: 21000, To: &to, GasTipCap: big.NewInt(1), GasFeeCap: big.NewInt(1)}
+	tx1 := MustSignNewTx(key1, signer, txdata)
+	tx2 := MustSignNewTx(key2, signer, txdata)
+
+	tx := new(Transaction)
+	if err := tx.UnmarshalBinary(mustMarshalBinary(t, tx1)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Sender(signer, tx); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.UnmarshalBinary(mustMarshalBinary(t, tx2)); err != nil {
+		t.Fatal(err)
+	}
+
+	// Readers may use the caches while they are being recomputed.
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			tx.Hash()
+			tx.Size()
+			Sender(signer, tx)
+		}()
+	}
+	tx.RecomputeHash()
+	wg.Wait()
+
+	from, err := Sender(signer, tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := crypto.PubkeyToAddress(key2.PublicKey); from != want {
+		t.Errorf("sender %x, want %x", from, want)
+	}
+}
+
+func mustMarshalBinary(t *testing.T, tx *Transaction) []byte {
+	t.Helper()
+	enc, err := tx.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return enc
+}
+
+func TestTransactionIsCreation(t *testing.T) {
+	to := common.HexToAddress("0x01")
+	tests := []struct {
+		name   string
+		txdata func(to *common.Address) TxData
+	}{
+		{"Legacy", func(to *common.Address) TxData {
+			return &LegacyTx{To: to, GasPrice: big.NewInt(1), Value: big.NewInt(0)}
+		}},
+		{"AccessList", func(to *common.Address) TxData {
+			return &AccessListTx{ChainID: big.NewInt(1), To: to, GasPrice: big.NewInt(1), Value: big.NewInt(0)}
+		}},
+		{"DynamicFee", func(to *common.Address) TxData {
+			return &DynamicFeeTx{ChainID: big.NewInt(1), To: to, GasTipCap: big.NewInt(1), GasFeeCap: big.NewInt(1), Value: big.NewInt(0)}
+		}},
+		{"Blob", func(to *common.Address) TxData {
+			return &BlobTx{ChainID: uint256.NewInt(1), To: to, GasTipCap: uint256.NewInt(1), GasFeeCap: uint256.NewInt(1), Value: uint256.NewInt(0)}
+		}},
+		{"Deposit", func(to *common.Address) TxData {
+			return &DepositTx{To: to, Value: big.NewInt(0), Gas: 21000}
+		}},
+	}
+	for _, test := range tests {
+		if tx := NewTx(test.txdata(nil)); !tx.IsCreation() {
+			t.Errorf("%s: creation not detected", test.name)
+		}
+		if tx := NewTx(test.txdata(&to)); tx.IsCreation() {
+			t.Errorf("%s: call detected as creation", test.name)
+		}
+	}
+}
+
+func TestTransactionReferencedAddresses(t *testing.T) {
+	var (
+		from = common.HexToAddress("0x01")
+		to   = common.HexToAddress("0x02")
+		a    = common.HexToAddress("0x03")
+		b    = common.HexToAddress("0x04")
+	)
+	tests := []struct {
+		name string
+		tx   *Transaction
+		want []common.Address
+	}{
+		{
+			name: "DynamicFee",
+			tx: NewTx(&DynamicFeeTx{ChainID: big.NewInt(1), To: &to, GasTipCap: big.NewInt(1), GasFeeCap: big.NewInt(1), Value: big.NewInt(0),
+				AccessList: AccessList{{Address: a}, {Address: to}, {Address: b}, {Address: a}}}),
+			want: []common.Address{to, a, b},
+		},
+		{
+			name: "DynamicFee creation",
+			tx:   NewTx(&DynamicFeeTx{ChainID: big.NewInt(1), GasTipCap: big.NewInt(1), GasFeeCap: big.NewInt(1), Value: big.NewInt(0)}),
+			want: nil,
+		},
+		{
+			name: "Deposit",
+			tx:   NewTx(&DepositTx{From: from, To: &to, Value: big.NewInt(0)}),
+			want: []common.Address{from, to},
+		},
+		{
+			name: "Deposit creation",
+			tx:   NewTx(&DepositTx{From: from, Value: big.NewInt(0)}),
+			want: []common.Address{from},
+		},
+	}
+	for _, test := range tests {
+		if have := test.tx.ReferencedAddresses(); !reflect.DeepEqual(have, test.want) {
+			t.Errorf("%s: have %v, want %v", test.name, have, test.want)
+		}
+	}
+}
+
+func TestTransactionEffectiveGasTip(t *testing.T) {
+	baseFee := big.NewInt(10)
+	tests := []struct {
+		name string
+		tx   TxData
+		want int64
+		err  error
+	}{
+		{"Legacy", &LegacyTx{GasPrice: big.NewInt(15)}, 5, nil},
+		{"AccessList", &AccessListTx{GasPrice: big.NewInt(15)}, 5, nil},
+		{"DynamicFee tip cap", &DynamicFeeTx{GasTipCap: big.NewInt(2), GasFeeCap: big.NewInt(15)}, 2, nil},
+		{"DynamicFee fee cap", &DynamicFeeTx{GasTipCap: big.NewInt(8), GasFeeCap: big.NewInt(15)}, 5, nil},
+		{"Blob", &BlobTx{GasTipCap: uint256.NewInt(8), GasFeeCap: uint256.NewInt(15)}, 5, nil},
+		{"Deposit", &DepositTx{}, 0, nil},
+		// The negative tip is returned along with the error.
+		{"Legacy below base fee", &LegacyTx{GasPrice: big.NewInt(7)}, -3, ErrGasFeeCapTooLow},
+		{"DynamicFee below base fee", &DynamicFeeTx{GasTipCap: big.NewInt(1), GasFeeCap: big.NewInt(7)}, -3, ErrGasFeeCapTooLow},
+	}
+	for _, test := range tests {
+		tip, err := NewTx(test.tx).EffectiveGasTip(baseFee)
+		if err != test.err {
+			t.Errorf("%s: error mismatch: have %v, want %v", test.name, err, test.err)
+		}
+		if tip.Int64() != test.want {
+			t.Errorf("%s: tip mismatch: have %v, want %d", test.name, tip, test.want)
+		}
+	}
+	// Without a base fee, the tip cap is returned.
+	if tip, err := NewTx(&DynamicFeeTx{GasTipCap: big.NewInt(2), GasFeeCap: big.NewInt(15)}).EffectiveGasTip(nil); err != nil || tip.Int64() != 2 {
+		t.Errorf("nil base fee: have %v, %v, want 2", tip, err)
+	}
+}
+
+func TestTransactionEqual(t *testing.T) {
+	txs := encodingTestTxs(t)
+	for i, tx := range txs {
+		enc, err := tx.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var a, b Transaction
+		if err := a.UnmarshalBinary(enc); err != nil {
+			t.Fatal(err)
+		}
+		if err := b.UnmarshalBinary(enc); err != nil {
+			t.Fatal(err)
+		}
+		a.Hash() // populate the cache of one copy only
+		if !a.Equal(&b) || !b.Equal(&a) {
+			t.Errorf("tx %d: decoded copies not equal", i)
+		}
+		if !tx.Equal(&a) {
+			t.Errorf("tx %d: decoded copy not equal to original", i)
+		}
+		for j, other := range txs {
+			if i != j && tx.Equal(other) {
+				t.Errorf("tx %d: equal to tx %d", i, j)
+			}
+		}
+		if tx.Equal(nil) {
+			t.Errorf("tx %d: equal to nil", i)
+		}
+	}
+
+	// Deposits only differing in their effective nonce are not equal.
+	const depositJSON = `{"type":"0x7e",%s"to":null,"gas":"0x1234","value":"0x1","input":"0x","sourceHash":"0x0000000000000000000000000000000000000000000000000000000000001234","from":"0x0000000000000000000000000000000000000001"}`
+	var withNonce, withoutNonce Transaction
+	if err := withNonce.UnmarshalJSON([]byte(fmt.Sprintf(depositJSON, `"nonce":"0x5",`))); err != nil {
+		t.Fatal(err)
+	}
+	if err := withoutNonce.UnmarshalJSON([]byte(fmt.Sprintf(depositJSON, ""))); err != nil {
+		t.Fatal(err)
+	}
+	if withNonce.Equal(&withoutNonce) {
+		t.Errorf("deposits with different effective nonces are equal")
+	}
+}
+
+func TestTransactionAccessListGas(t *testing.T) {
+	tx := NewTx(&DynamicFeeTx{
+		ChainID:   big.NewInt(1),
+		GasTipCap: big.NewInt(1),
+		GasFeeCap: big.NewInt(1),
+		AccessList: AccessList{
+			{Address: common.HexToAddress("0x01"), StorageKeys: []common.Hash{{0x01}, {0x02}}},
+			{Address: common.HexToAddress("0x02"), StorageKeys: []common.Hash{{0x01}, {0x02}, {0x03}}},
+		},
+	})
+	if have, want := tx.AccessListGas(), uint64(2*2400+5*1900); have != want {
+		t.Errorf("wrong access list gas: have %d, want %d", have, want)
+	}
+
+	legacy := NewTx(&LegacyTx{GasPrice: big.NewInt(1)})
+	if have := legacy.AccessListGas(); have != 0 {
+		t.Errorf("legacy tx: non-zero access list gas %d", have)
+	}
+	deposit := NewTx(&DepositTx{Value: big.NewInt(1)})
+	if have := deposit.AccessListGas(); have != 0 {
+		t.Errorf("deposit tx: non-zero access list gas %d", have)
+	}
+}
+
+func TestTransactionCountsAgainstGasLimit(t *testing.T) {
+	tests := []struct {
+		name string
+		tx   *Transaction
+		want bool
+	}{
+		{name: "system deposit", tx: NewTx(&DepositTx{Value: big.NewInt(0), IsSystemTransaction: true}), want: false},
+		{name: "user deposit", tx: NewTx(&DepositTx{Value: big.NewInt(0)}), want: true},
+		{name: "dynamic fee", tx: NewTx(&DynamicFeeTx{GasTipCap: big.NewInt(1), GasFeeCap: big.NewInt(1)}), want: true},
+		{name: "legacy", tx: NewTx(&LegacyTx{GasPrice: big.NewInt(1)}), want: true},
+	}
+	for _, test := range tests {
+		if have := test.tx.CountsAgainstGasLimit(); have != test.want {
+			t.Errorf("%s: CountsAgainstGasLimit() = %v, want %v", test.name, have, test.want)
+		}
+	}
+}
+
+func TestTransactionWithBlobSidecar(t *testing.T) {
+	sidecar := &BlobTxSidecar{
+		Blobs:       make([]kzg4844.Blob, 2),
+		Commitments: []kzg4844.Commitment{{0x01}, {0x02}},
+		Proofs:      make([]kzg4844.Proof, 2),
+	}
+	tx := NewTx(&BlobTx{
+		ChainID:    uint256.NewInt(1),
+		GasTipCap:  uint256.NewInt(1),
+		GasFeeCap:  uint256.NewInt(1),
+		Value:      uint256.NewInt(0),
+		BlobFeeCap: uint256.NewInt(1),
+		BlobHashes: sidecar.BlobHashes(),
+	})
+	hash := tx.Hash()
+
+	with, err := tx.WithBlobSidecar(sidecar)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if with.BlobTxSidecar() != sidecar {
+		t.Errorf("sidecar not attached")
+	}
+	if tx.BlobTxSidecar() != nil {
+		t.Errorf("sidecar attached to the original transaction")
+	}
+	if with.Hash() != hash {
+		t.Errorf("attaching the sidecar changed the hash: have %v, want %v", with.Hash(), hash)
+	}
+	without := with.WithoutBlobSidecar()
+	if without.BlobTxSidecar() != nil {
+		t.Errorf("sidecar not detached")
+	}
+	if with.BlobTxSidecar() != sidecar {
+		t.Errorf("sidecar detached from the original transaction")
+	}
+	if without.Hash() != hash {
+		t.Errorf("detaching the sidecar changed the hash: have %v, want %v", without.Hash(), hash)
+	}
+	if without.WithoutBlobSidecar() != without {
+		t.Errorf("detaching a missing sidecar created a copy")
+	}
+
+	// The consensus encoding is the same with and without the sidecar.
+	want, err := tx.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tx := range []*Transaction{tx, with, without} {
+		enc, err := tx.MarshalBinaryWithoutSidecar()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(enc, want) {
+			t.Errorf("wrong encoding without sidecar: have %x, want %x", enc, want)
+		}
+	}
+
+	// Sidecars not matching the blob hashes are rejected.
+	short := &BlobTxSidecar{Blobs: sidecar.Blobs[:1], Commitments: sidecar.Commitments[:1], Proofs: sidecar.Proofs[:1]}
+	if _, err := tx.WithBlobSidecar(short); err == nil {
+		t.Errorf("expected error for sidecar with too few blobs")
+	}
+	swapped := &BlobTxSidecar{Blobs: sidecar.Blobs, Commitments: []kzg4844.Commitment{{0x02}, {0x01}}, Proofs: sidecar.Proofs}
+	if _, err := tx.WithBlobSidecar(swapped); err == nil {
+		t.Errorf("expected error for sidecar with mismatching commitments")
+	}
+	if _, err := tx.WithBlobSidecar(nil); err == nil {
+		t.Errorf("expected error for nil sidecar")
+	}
+	if _, err := NewTx(&LegacyTx{GasPrice: big.NewInt(1)}).WithBlobSidecar(sidecar); !errors.Is(err, ErrTxTypeNotSupported) {
+		t.Errorf("legacy tx: wrong error %v, want %v", err, ErrTxTypeNotSupported)
+	}
+}
+
+func TestTransactionTypeInPayload(t *testing.T) {
+	for _, tx := range []*Transaction{
+		NewTx(&DepositTx{SourceHash: common.HexToHash("0x1234"), From: common.HexToAddress("0x1"), Gas: 1000, Value: big.NewInt(1), Data: []byte{0x01}}),
+		NewTx(&DynamicFeeTx{ChainID: big.NewInt(1), Nonce: 1, GasTipCap: big.NewInt(1), GasFeeCap: big.NewInt(2), Gas: 21000, To: &testAddr, Value: big.NewInt(1)}),
+	} {
+		enc, err := tx.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var dec Transaction
+		if err := dec.UnmarshalBinary(enc); err != nil {
+			t.Fatalf("tx type %d: failed to decode well-formed envelope: %v", tx.Type(), err)
+		}
+
+		// Move the type byte into the payload list.
+		elems, _, err := rlp.SplitList(enc[1:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		buf := rlp.NewEncoderBuffer(nil)
+		l := buf.List()
+		buf.Write([]byte{tx.Type()})
+		buf.Write(elems)
+		buf.ListEnd(l)
+		if err := dec.UnmarshalBinary(buf.ToBytes()); !errors.Is(err, ErrTxTypeInPayload) {
+			t.Errorf("tx type %d: wrong error for malformed envelope: have %v, want %v", tx.Type(), err, ErrTxTypeInPayload)
+		}
+	}
+
+	// Malformed legacy transactions keep their RLP decoding error.
+	if err := new(Transaction).UnmarshalBinary([]byte{0xc2, 0x02, 0x01}); err == nil || errors.Is(err, ErrTxTypeInPayload) {
+		t.Errorf("wrong error for short legacy transaction: %v", err)
+	}
+}
+
+func TestTransactionAnonymize(t *testing.T) {
+	for _, tx := range encodingTestTxs(t) {
+		hash := tx.Hash()
+		anon := tx.Anonymize()
+
+		if anon.Type() != tx.Type() {
+			t.Errorf("tx type %d: type changed to %d", tx.Type(), anon.Type())
+		}
+		if anon.Size() != tx.Size() {
+			t.Errorf("tx type %d: size changed from %d to %d", tx.Type(), tx.Size(), anon.Size())
+		}
+		if anon.Gas() != tx.Gas() || anon.Value().Cmp(tx.Value()) != 0 || anon.Nonce() != tx.Nonce() {
+			t.Errorf("tx type %d: gas, value or nonce changed", tx.Type())
+		}
+		if !bytes.Equal(anon.Data(), make([]byte, len(tx.Data()))) {
+			t.Errorf("tx type %d: calldata not zeroed: %x", tx.Type(), anon.Data())
+		}
+		if (anon.To() == nil) != (tx.To() == nil) || anon.To() != nil && *anon.To() != (common.Address{}) {
+			t.Errorf("tx type %d: recipient not zeroed: %v", tx.Type(), anon.To())
+		}
+		if len(anon.AccessList()) != len(tx.AccessList()) || anon.AccessList().StorageKeys() != tx.AccessList().StorageKeys() {
+			t.Errorf("tx type %d: access list shape changed", tx.Type())
+		}
+		for _, tuple := range anon.AccessList() {
+			if tuple.Address != (common.Address{}) {
+				t.Errorf("tx type %d: access list address not zeroed", tx.Type())
+			}
+			for _, key := range tuple.StorageKeys {
+				if key != (common.Hash{}) {
+					t.Errorf("tx type %d: access list storage key not zeroed", tx.Type())
+				}
+			}
+		}
+		if tx.IsDepositTx() {
+			if anon.SourceHash() != (common.Hash{}) {
+				t.Errorf("deposit: source hash not zeroed")
+			}
+			if from, _ := Sender(LatestSignerForChainID(nil), anon); from != (common.Address{}) {
+				t.Errorf("deposit: sender not zeroed")
+			}
+			if anon.IsSystemTx() != tx.IsSystemTx() {
+				t.Errorf("deposit: system flag changed")
+			}
+		}
+		if tx.Hash() != hash {
+			t.Errorf("tx type %d: original transaction modified", tx.Type())
+		}
+	}
+}
+
+func TestDecodeTransactionsGzip(t *testing.T) {
+	txs := encodingTestTxs(t)
+	enc, err := rlp.EncodeToBytes(Transactions(txs))
+	if err != nil {
+		t.Fatal(err)
+	}
+	compress := func(data []byte) []byte {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		zw.Write(data)
+		zw.Close()
+		return buf.Bytes()
+	}
+	batch := compress(enc)
+
+	have, err := DecodeTransactionsGzip(bytes.NewReader(batch))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(have) != len(txs) {
+		t.Fatalf("wrong number of transactions: have %d, want %d", len(have), len(txs))
+	}
+	for i := range txs {
+		if have[i].Hash() != txs[i].Hash() {
+			t.Errorf("tx %d: wrong hash: have %x, want %x", i, have[i].Hash(), txs[i].Hash())
+		}
+	}
+
+	// Batches exceeding the limit are rejected, while those at the limit pass.
+	defer func(max int64) { MaxGzipTxBatchBytes = max }(MaxGzipTxBatchBytes)
+	MaxGzipTxBatchBytes = int64(len(enc))
+	if _, err := DecodeTransactionsGzip(bytes.NewReader(batch)); err != nil {
+		t.Errorf("batch at the limit rejected: %v", err)
+	}
+	MaxGzipTxBatchBytes = int64(len(enc)) - 1
+	if _, err := DecodeTransactionsGzip(bytes.NewReader(batch)); !errors.Is(err, ErrTxBatchTooLarge) {
+		t.Errorf("wrong error for oversized batch: have %v, want %v", err, ErrTxBatchTooLarge)
+	}
+	MaxGzipTxBatchBytes = 1024
+	bomb := compress(make([]byte, 1024*1024))
+	if _, err := DecodeTransactionsGzip(bytes.NewReader(bomb)); err == nil {
+		t.Errorf("no error for decompression bomb")
+	}
+	MaxGzipTxBatchBytes = 0
+
+	// Corrupt and trailing data.
+	if _, err := DecodeTransactionsGzip(bytes.NewReader(enc)); err == nil {
+		t.Errorf("no error for uncompressed input")
+	}
+	corrupt := common.CopyBytes(batch)
+	corrupt[len(corrupt)-5] ^= 0xff // CRC-32 of the trailer
+	if _, err := DecodeTransactionsGzip(bytes.NewReader(corrupt)); err == nil {
+		t.Errorf("no error for corrupt checksum")
+	}
+	if _, err := DecodeTransactionsGzip(bytes.NewReader(batch[:len(batch)/2])); err == nil {
+		t.Errorf("no error for truncated input")
+	}
+	if _, err := DecodeTransactionsGzip(bytes.NewReader(compress(append(enc, 0x01)))); err == nil {
+		t.Errorf("no error for trailing data")
+	}
+}
+
+func TestTransactionMarshalBinaryWithoutSidecar(t *testing.T) {
+	for _, tx := range encodingTestTxs(t) {
+		want, err := tx.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		have, err := tx.MarshalBinaryWithoutSidecar()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(have, want) {
+			t.Errorf("tx type %d: encoding mismatch: have %x, want %x", tx.Type(), have, want)
+		}
+	}
+}
+
+func TestTransactionValidForConfig(t *testing.T) {
+	cancun := uint64(1000)
+	config := *params.TestChainConfig
+	config.CancunTime = &cancun
+
+	blob := NewTx(&BlobTx{BlobHashes: []common.Hash{{0x01}}})
+	if err := blob.ValidForConfig(&config, cancun-1); !errors.Is(err, ErrTxTypeNotSupported) {
+		t.Errorf("blob tx before Cancun: wrong error %v", err)
+	}
+	if err := blob.ValidForConfig(&config, cancun); err != nil {
+		t.Errorf("blob tx at Cancun: %v", err)
+	}
+	for _, tx := range []*Transaction{
+		NewTx(&LegacyTx{}),
+		NewTx(&AccessListTx{}),
+		NewTx(&DynamicFeeTx{}),
+	} {
+		if err := tx.ValidForConfig(&config, 0); err != nil {
+			t.Errorf("tx type %d: %v", tx.Type(), err)
+		}
+	}
+	preLondon := config
+	preLondon.LondonBlock = nil
+	if err := NewTx(&DynamicFeeTx{}).ValidForConfig(&preLondon, 0); !errors.Is(err, ErrTxTypeNotSupported) {
+		t.Errorf("dynamic fee tx without London: wrong error %v", err)
+	}
+
+	deposit := NewTx(&DepositTx{Value: big.NewInt(0)})
+	if err := deposit.ValidForConfig(&config, 0); !errors.Is(err, ErrTxTypeNotSupported) {
+		t.Errorf("deposit on non-Optimism chain: wrong error %v", err)
+	}
+	optimism := config
+	optimism.Optimism = &params.OptimismConfig{EIP1559Elasticity: 6, EIP1559Denominator: 50}
+	optimism.BedrockBlock = big.NewInt(0)
+	if err := deposit.ValidForConfig(&optimism, 0); err != nil {
+		t.Errorf("deposit on Optimism chain: %v", err)
+	}
+}
+
+func TestTransactionGasLimit(t *testing.T) {
+	for _, tx := range encodingTestTxs(t) {
+		if tx.GasLimit() != tx.Gas() {
+			t.Errorf("tx type %d: gas limit %d differs from gas %d", tx.Type(), tx.GasLimit(), tx.Gas())
+		}
+		if tx.GasLimit() == 0 {
+			t.Errorf("tx type %d: zero gas limit", tx.Type())
+		}
+	}
+}
+
+func TestPostDecodeHook(t *testing.T) {
+	errRejected := errors.New("deposits rejected")
+	var calls []byte
+	PostDecodeHook = func(tx *Transaction) error {
+		calls = append(calls, tx.Type())
+		if tx.IsDepositTx() {
+			return errRejected
+		}
+		return nil
+	}
+	defer func() { PostDecodeHook = nil }()
+
+	key, _ := crypto.GenerateKey()
+	legacy, err := SignNewTx(key, HomesteadSigner{}, &LegacyTx{Nonce: 1, GasPrice: big.NewInt(1), Gas: 21000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	deposit := NewTx(&DepositTx{SourceHash: common.Hash{0xaa}, From: common.HexToAddress("0x01"), Value: big.NewInt(1), Gas: 100_000})
+
+	decoders := map[string]func(tx, into *Transaction) error{
+		"JSON": func(tx, into *Transaction) error {
+			enc, _ := tx.MarshalJSON()
+			return into.UnmarshalJSON(enc)
+		},
+		"Binary": func(tx, into *Transaction) error {
+			enc, _ := tx.MarshalBinary()
+			return into.UnmarshalBinary(enc)
+		},
+		"BinaryStrict": func(tx, into *Transaction) error {
+			enc, _ := tx.MarshalBinary()
+			return into.UnmarshalBinaryStrict(enc)
+		},
+		"RLP": func(tx, into *Transaction) error {
+			enc, _ := rlp.EncodeToBytes(tx)
+			return rlp.DecodeBytes(enc, into)
+		},
+	}
+	for name, decode := range decoders {
+		calls = nil
+		into := new(Transaction)
+		if err := decode(legacy, into); err != nil {
+			t.Errorf("%s: legacy rejected: %v", name, err)
+		}
+		if err := decode(deposit, into); !errors.Is(err, errRejected) {
+			t.Errorf("%s: deposit error %v, want %v", name, err, errRejected)
+		}
+		if want := []byte{LegacyTxType, DepositTxType}; !bytes.Equal(calls, want) {
+			t.Errorf("%s: hook called for types %v, want %v", name, calls, want)
+		}
+		// The rejected deposit is not stored in the receiver.
+		if into.Type() != LegacyTxType || into.Nonce() != legacy.Nonce() {
+			t.Errorf("%s: receiver modified by rejected decode: type %d, nonce %d", name, into.Type(), into.Nonce())
+		}
+	}
+
+	// Constructing transactions does not run the hook.
+	calls = nil
+	NewTx(&DepositTx{})
+	if len(calls) != 0 {
+		t.Errorf("hook called by NewTx")
+	}
+}
+
+func TestTransactionL1DataGas(t *testing.T) {
+	to := common.HexToAddress("0x01")
+	tests := []struct {
+		name           string
+		tx             *Transaction
+		zeros, nonZero uint64
+	}{
+		{
+			// e2 80 01 825208 94<19 zero bytes>01 80 83000001 80 80 80
+			name:  "Legacy",
+			tx:    NewTx(&LegacyTx{GasPrice: big.NewInt(1), Gas: 21000, To: &to, Data: []byte{0, 0, 1}}),
+			zeros: 21, nonZero: 14,
+		},
+		{
+			// 02 e3 01 80 80 80 80 94<19 zero bytes>01 80 83000001 c0 80 80 80
+			name:  "DynamicFee",
+			tx:    NewTx(&DynamicFeeTx{ChainID: big.NewInt(1), To: &to, Data: []byte{0, 0, 1}}),
+			zeros: 21, nonZero: 16,
+		},
+		{
+			name: "Deposit",
+			tx:   NewTx(&DepositTx{SourceHash: common.Hash{0xaa}, From: to, To: &to, Gas: 21000, Data: []byte{0, 0, 1}}),
+		},
+	}
+	for _, test := range tests {
+		zeros, nonZero := test.tx.L1DataGas()
+		if zeros != test.zeros || nonZero != test.nonZero {
+			t.Errorf("%s: have %d zero and %d non-zero bytes, want %d and %d", test.name, zeros, nonZero, test.zeros, test.nonZero)
+		}
+		if gas := test.tx.RollupDataGas(); gas.Zeroes != zeros || gas.Ones != nonZero {
+			t.Errorf("%s: counts differ from rollup data gas %+v", test.name, gas)
+		}
+	}
+}
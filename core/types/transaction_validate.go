@@ -0,0 +1,162 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// requiredTxJSONFields are the JSON fields UnmarshalJSON requires for each of
+// the built-in transaction types.
+var requiredTxJSONFields = map[byte][]string{
+	LegacyTxType:     {"nonce", "gas", "gasPrice", "value", "input", "v", "r", "s"},
+	AccessListTxType: {"chainId", "nonce", "gas", "gasPrice", "value", "input", "v", "r", "s"},
+	DynamicFeeTxType: {"chainId", "nonce", "gas", "maxPriorityFeePerGas", "maxFeePerGas", "value", "input", "v", "r", "s"},
+	BlobTxType:       {"chainId", "nonce", "gas", "maxPriorityFeePerGas", "maxFeePerGas", "maxFeePerDataGas", "value", "input", "blobVersionedHashes", "v", "r", "s"},
+	DepositTxType:    {"gas", "value", "input", "from", "sourceHash"},
+}
+
+// optionalTxJSONFields are the JSON fields UnmarshalJSON accepts, but does not
+// require, for each of the built-in transaction types, apart from 'type'.
+var optionalTxJSONFields = map[byte][]string{
+	LegacyTxType:     {"chainId", "to"},
+	AccessListTxType: {"to", "accessList"},
+	DynamicFeeTxType: {"to", "accessList"},
+	BlobTxType:       {"to", "accessList", "blobs", "commitments", "proofs"},
+	DepositTxType:    {"nonce", "to", "gasPrice", "mint", "isSystemTx", "v", "r", "s"},
+}
+
+// Kinds of transaction JSON field values, as reported in FieldSpec.
+const (
+	FieldKindQuantity   = "quantity"   // hex encoded integer without leading zeros
+	FieldKindData       = "data"       // hex encoded byte string
+	FieldKindDataList   = "dataList"   // array of hex encoded byte strings
+	FieldKindAddress    = "address"    // hex encoded 20-byte address
+	FieldKindHash       = "hash"       // hex encoded 32-byte hash
+	FieldKindHashList   = "hashList"   // array of hex encoded 32-byte hashes
+	FieldKindAccessList = "accessList" // array of {address, storageKeys} objects
+	FieldKindBool       = "bool"       // JSON boolean
+)
+
+// txJSONFieldKinds are the kinds of the values of the transaction JSON fields.
+var txJSONFieldKinds = map[string]string{
+	"type":                 FieldKindQuantity,
+	"chainId":              FieldKindQuantity,
+	"nonce":                FieldKindQuantity,
+	"to":                   FieldKindAddress,
+	"gas":                  FieldKindQuantity,
+	"gasPrice":             FieldKindQuantity,
+	"maxPriorityFeePerGas": FieldKindQuantity,
+	"maxFeePerGas":         FieldKindQuantity,
+	"maxFeePerDataGas":     FieldKindQuantity,
+	"value":                FieldKindQuantity,
+	"input":                FieldKindData,
+	"accessList":           FieldKindAccessList,
+	"blobVersionedHashes":  FieldKindHashList,
+	"blobs":                FieldKindDataList,
+	"commitments":          FieldKindDataList,
+	"proofs":               FieldKindDataList,
+	"v":                    FieldKindQuantity,
+	"r":                    FieldKindQuantity,
+	"s":                    FieldKindQuantity,
+	"sourceHash":           FieldKindHash,
+	"from":                 FieldKindAddress,
+	"mint":                 FieldKindQuantity,
+	"isSystemTx":           FieldKindBool,
+}
+
+// FieldSpec describes a field of the JSON representation of a transaction.
+type FieldSpec struct {
+	Kind     string // one of the FieldKind constants
+	Required bool
+}
+
+// TxJSONSchema returns the fields UnmarshalJSON accepts for the given built-in
+// transaction type, keyed by their JSON name. Fields not listed are ignored by
+// the decoder. The schema does not capture checks across fields, such as the
+// signature or deposit gas price checks.
+func TxJSONSchema(typ byte) (map[string]FieldSpec, error) {
+	required, ok := requiredTxJSONFields[typ]
+	if !ok {
+		return nil, fmt.Errorf("%w: no schema for type %d", ErrTxTypeNotSupported, typ)
+	}
+	// Legacy transactions are the default if 'type' is omitted.
+	schema := map[string]FieldSpec{
+		"type": {Kind: txJSONFieldKinds["type"], Required: typ != LegacyTxType},
+	}
+	for _, name := range required {
+		schema[name] = FieldSpec{Kind: txJSONFieldKinds[name], Required: true}
+	}
+	for _, name := range optionalTxJSONFields[typ] {
+		schema[name] = FieldSpec{Kind: txJSONFieldKinds[name]}
+	}
+	return schema, nil
+}
+
+// ValidateTxJSONFields checks a JSON-encoded transaction like UnmarshalJSON,
+// but instead of stopping at the first problem it reports every missing
+// required field and every malformed field of the transaction type at once.
+// Only if all fields are present and well-formed are the remaining checks of
+// UnmarshalJSON applied, as they may depend on several fields. It returns nil
+// if UnmarshalJSON would accept the input.
+func ValidateTxJSONFields(input []byte) []error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(input, &fields); err != nil {
+		return []error{err}
+	}
+	if fields == nil {
+		return []error{errTxNull}
+	}
+	var typ hexutil.Uint64
+	if raw, ok := fields["type"]; ok {
+		if err := json.Unmarshal(raw, &typ); err != nil {
+			return []error{fmt.Errorf("invalid field 'type' in transaction: %w", err)}
+		}
+	}
+	if _, ok := txTypeCodecs[byte(typ)]; typ > 0xff || !ok {
+		return []error{ErrTxTypeNotSupported}
+	}
+
+	var errs []error
+	for _, name := range requiredTxJSONFields[byte(typ)] {
+		if raw, ok := fields[name]; !ok || string(raw) == "null" {
+			errs = append(errs, fmt.Errorf("missing required field '%s' in transaction", name))
+		}
+	}
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		field, _ := json.Marshal(map[string]json.RawMessage{name: fields[name]})
+		if err := json.Unmarshal(field, new(TxJSON)); err != nil {
+			errs = append(errs, fmt.Errorf("invalid field '%s' in transaction: %w", name, err))
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	if err := new(Transaction).UnmarshalJSON(input); err != nil {
+		return []error{err}
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateTxJSONFields(t *testing.T) {
+	for _, tx := range encodingTestTxs(t) {
+		enc, err := json.Marshal(tx)
+		require.NoError(t, err)
+		require.Empty(t, ValidateTxJSONFields(enc), "tx type %d", tx.Type())
+	}
+
+	// All missing fields are reported at once.
+	const missing = `{"type":"0x2","chainId":"0x1","to":null,"maxFeePerGas":"0x1","value":"0x0","input":"0x","v":"0x0","r":"0x0","s":"0x0"}`
+	require.Error(t, new(Transaction).UnmarshalJSON([]byte(missing)))
+	errs := ValidateTxJSONFields([]byte(missing))
+	require.Len(t, errs, 3)
+	require.EqualError(t, errs[0], "missing required field 'nonce' in transaction")
+	require.EqualError(t, errs[1], "missing required field 'gas' in transaction")
+	require.EqualError(t, errs[2], "missing required field 'maxPriorityFeePerGas' in transaction")
+
+	// Missing and malformed fields are reported together.
+	const malformed = `{"type":"0x2","chainId":"0x1","to":"0x01","maxFeePerGas":"0x1","maxPriorityFeePerGas":"1","value":"0x0","input":"0x","v":"0x0","r":"0x0","s":"0x0"}`
+	errs = ValidateTxJSONFields([]byte(malformed))
+	require.Len(t, errs, 4)
+	require.EqualError(t, errs[0], "missing required field 'nonce' in transaction")
+	require.EqualError(t, errs[1], "missing required field 'gas' in transaction")
+	require.ErrorContains(t, errs[2], "invalid field 'maxPriorityFeePerGas' in transaction")
+	require.ErrorContains(t, errs[3], "invalid field 'to' in transaction")
+
+	// Checks across fields only run once all fields are valid.
+	const deposit = `{"type":"0x7e","gasPrice":"0x1","to":null,"gas":"0x1234","value":"0x1","input":"0x","sourceHash":"0x0000000000000000000000000000000000000000000000000000000000001234","from":"0x0000000000000000000000000000000000000001"}`
+	errs = ValidateTxJSONFields([]byte(deposit))
+	require.Len(t, errs, 1)
+	require.EqualError(t, errs[0], "deposit transaction GasPrice must be 0")
+
+	require.Equal(t, []error{errTxNull}, ValidateTxJSONFields([]byte("null")))
+	require.Equal(t, []error{ErrTxTypeNotSupported}, ValidateTxJSONFields([]byte(`{"type":"0x50"}`)))
+	require.Len(t, ValidateTxJSONFields([]byte(`{"type":true}`)), 1)
+}
+
+func TestTxJSONSchema(t *testing.T) {
+	schema, err := TxJSONSchema(DepositTxType)
+	require.NoError(t, err)
+	for _, name := range []string{"type", "sourceHash", "from", "gas", "value", "input"} {
+		require.True(t, schema[name].Required, "field %s", name)
+	}
+	for _, name := range []string{"isSystemTx", "mint", "nonce", "to"} {
+		require.Contains(t, schema, name)
+		require.False(t, schema[name].Required, "field %s", name)
+	}
+	require.Equal(t, FieldKindHash, schema["sourceHash"].Kind)
+	require.Equal(t, FieldKindBool, schema["isSystemTx"].Kind)
+	require.NotContains(t, schema, "maxFeePerGas")
+
+	legacy, err := TxJSONSchema(LegacyTxType)
+	require.NoError(t, err)
+	require.False(t, legacy["type"].Required)
+
+	// Every field of every schema has a kind, and encoded transactions only
+	// contain known fields apart from the hash.
+	for _, tx := range encodingTestTxs(t) {
+		schema, err := TxJSONSchema(tx.Type())
+		require.NoError(t, err)
+		for name, spec := range schema {
+			require.NotEmpty(t, spec.Kind, "type %d field %s", tx.Type(), name)
+		}
+		enc, err := json.Marshal(tx)
+		require.NoError(t, err)
+		var fields map[string]json.RawMessage
+		require.NoError(t, json.Unmarshal(enc, &fields))
+		for name, raw := range fields {
+			if name == "hash" || string(raw) == "null" {
+				continue
+			}
+			require.Contains(t, schema, name, "type %d", tx.Type())
+		}
+		for name, spec := range schema {
+			if spec.Required {
+				require.Contains(t, fields, name, "type %d", tx.Type())
+			}
+		}
+	}
+
+	_, err = TxJSONSchema(0x50)
+	require.ErrorIs(t, err, ErrTxTypeNotSupported)
+}
@@ -17,9 +17,11 @@
 package types
 
 import (
+	"crypto/sha256"
 	"math/big"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto/kzg4844"
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/holiman/uint256"
 )
@@ -38,12 +40,52 @@ type BlobTx struct {
 	BlobFeeCap *uint256.Int // a.k.a. maxFeePerDataGas
 	BlobHashes []common.Hash
 
+	// A blob transaction can optionally carry its blobs in a sidecar. The
+	// sidecar is not part of the consensus encoding of the transaction.
+	Sidecar *BlobTxSidecar `rlp:"-"`
+
 	// Signature values
 	V *uint256.Int `json:"v" gencodec:"required"`
 	R *uint256.Int `json:"r" gencodec:"required"`
 	S *uint256.Int `json:"s" gencodec:"required"`
 }
 
+// BlobTxSidecar contains the blobs of a blob transaction, which are carried
+// alongside the transaction in its network form but are not part of the
+// consensus encoding.
+type BlobTxSidecar struct {
+	Blobs       []kzg4844.Blob       // Blobs needed by the blob pool
+	Commitments []kzg4844.Commitment // Commitments needed by the blob pool
+	Proofs      []kzg4844.Proof      // Proofs needed by the blob pool
+}
+
+// BlobHashes computes the blob hashes of the given blobs.
+func (sc *BlobTxSidecar) BlobHashes() []common.Hash {
+	h := make([]common.Hash, len(sc.Commitments))
+	for i := range sc.Commitments {
+		h[i] = blobHash(&sc.Commitments[i])
+	}
+	return h
+}
+
+// blobHash computes the versioned hash of a KZG commitment.
+func blobHash(commit *kzg4844.Commitment) common.Hash {
+	hasher := sha256.New()
+	hasher.Write(commit[:])
+	var vhash common.Hash
+	hasher.Sum(vhash[:0])
+	vhash[0] = blobCommitmentVersionKZG
+	return vhash
+}
+
+// blobCommitmentVersionKZG is the version byte for the point evaluation precompile.
+const blobCommitmentVersionKZG uint8 = 0x01
+
+// MaxBlobVersionedHashesPerTx is the maximum number of blob versioned hashes, and
+// thus blobs, a transaction can carry. It is bounded by the data gas available
+// in a block.
+const MaxBlobVersionedHashesPerTx = params.MaxDataGasPerBlock / params.BlobTxDataGasPerBlob
+
 // copy creates a deep copy of the transaction data and initializes all fields.
 func (tx *BlobTx) copy() TxData {
 	cpy := &BlobTx{
@@ -90,6 +132,13 @@ func (tx *BlobTx) copy() TxData {
 	if tx.S != nil {
 		cpy.S.Set(tx.S)
 	}
+	if tx.Sidecar != nil {
+		cpy.Sidecar = &BlobTxSidecar{
+			Blobs:       append([]kzg4844.Blob(nil), tx.Sidecar.Blobs...),
+			Commitments: append([]kzg4844.Commitment(nil), tx.Sidecar.Commitments...),
+			Proofs:      append([]kzg4844.Proof(nil), tx.Sidecar.Proofs...),
+		}
+	}
 	return cpy
 }
 
@@ -0,0 +1,244 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package txcodec
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/fxamacker/cbor/v2"
+)
+
+// cborEncMode encodes with the core deterministic encoding rules of RFC 8949,
+// most notably sorted map keys, so that repeated encodings are byte-identical.
+// Nil slices are encoded as empty, so that e.g. empty calldata does not decode
+// as a missing field.
+var cborEncMode = func() cbor.EncMode {
+	opts := cbor.CoreDetEncOptions()
+	opts.NilContainers = cbor.NilContainerAsEmpty
+	mode, err := opts.EncMode()
+	if err != nil {
+		panic(err)
+	}
+	return mode
+}()
+
+// txCBOR is the CBOR representation of transactions. It has the same field
+// layout as types.TxJSON, but stores quantities as CBOR integers and binary
+// values as CBOR byte strings instead of hex strings.
+type txCBOR struct {
+	Type uint64 `cbor:"type"`
+
+	ChainID              *big.Int           `cbor:"chainId,omitempty"`
+	Nonce                *uint64            `cbor:"nonce,omitempty"`
+	To                   []byte             `cbor:"to,omitempty"`
+	Gas                  *uint64            `cbor:"gas,omitempty"`
+	GasPrice             *big.Int           `cbor:"gasPrice,omitempty"`
+	MaxPriorityFeePerGas *big.Int           `cbor:"maxPriorityFeePerGas,omitempty"`
+	MaxFeePerGas         *big.Int           `cbor:"maxFeePerGas,omitempty"`
+	MaxFeePerDataGas     *big.Int           `cbor:"maxFeePerDataGas,omitempty"`
+	Value                *big.Int           `cbor:"value,omitempty"`
+	Input                *[]byte            `cbor:"input,omitempty"`
+	AccessList           *[]accessTupleCBOR `cbor:"accessList,omitempty"`
+	BlobVersionedHashes  [][]byte           `cbor:"blobVersionedHashes,omitempty"`
+	Blobs                [][]byte           `cbor:"blobs,omitempty"`
+	Commitments          [][]byte           `cbor:"commitments,omitempty"`
+	Proofs               [][]byte           `cbor:"proofs,omitempty"`
+	V                    *big.Int           `cbor:"v,omitempty"`
+	R                    *big.Int           `cbor:"r,omitempty"`
+	S                    *big.Int           `cbor:"s,omitempty"`
+
+	// Deposit transaction fields
+	SourceHash []byte   `cbor:"sourceHash,omitempty"`
+	From       []byte   `cbor:"from,omitempty"`
+	Mint       *big.Int `cbor:"mint,omitempty"`
+	IsSystemTx *bool    `cbor:"isSystemTx,omitempty"`
+
+	// Only used for encoding:
+	Hash []byte `cbor:"hash,omitempty"`
+}
+
+// accessTupleCBOR is the CBOR representation of an access list entry.
+type accessTupleCBOR struct {
+	Address     []byte   `cbor:"address"`
+	StorageKeys [][]byte `cbor:"storageKeys"`
+}
+
+// MarshalCBOR marshals a transaction as deterministic CBOR with a hash.
+func MarshalCBOR(tx *types.Transaction) ([]byte, error) {
+	enc := tx.EncodeTxJSON()
+	return cborEncMode.Marshal(&txCBOR{
+		Type:                 uint64(enc.Type),
+		ChainID:              (*big.Int)(enc.ChainID),
+		Nonce:                (*uint64)(enc.Nonce),
+		To:                   addressToCBOR(enc.To),
+		Gas:                  (*uint64)(enc.Gas),
+		GasPrice:             (*big.Int)(enc.GasPrice),
+		MaxPriorityFeePerGas: (*big.Int)(enc.MaxPriorityFeePerGas),
+		MaxFeePerGas:         (*big.Int)(enc.MaxFeePerGas),
+		MaxFeePerDataGas:     (*big.Int)(enc.MaxFeePerDataGas),
+		Value:                (*big.Int)(enc.Value),
+		Input:                (*[]byte)(enc.Input),
+		AccessList:           accessListToCBOR(enc.AccessList),
+		BlobVersionedHashes:  hashesToCBOR(enc.BlobVersionedHashes),
+		Blobs:                bytesListToCBOR(enc.Blobs),
+		Commitments:          bytesListToCBOR(enc.Commitments),
+		Proofs:               bytesListToCBOR(enc.Proofs),
+		V:                    (*big.Int)(enc.V),
+		R:                    (*big.Int)(enc.R),
+		S:                    (*big.Int)(enc.S),
+		SourceHash:           hashToCBOR(enc.SourceHash),
+		From:                 addressToCBOR(enc.From),
+		Mint:                 (*big.Int)(enc.Mint),
+		IsSystemTx:           enc.IsSystemTx,
+		Hash:                 enc.Hash.Bytes(),
+	})
+}
+
+// UnmarshalCBOR unmarshals a transaction from CBOR, applying the same checks as
+// UnmarshalJSON.
+func UnmarshalCBOR(input []byte) (*types.Transaction, error) {
+	var dec txCBOR
+	if err := cbor.Unmarshal(input, &dec); err != nil {
+		return nil, err
+	}
+	var (
+		enc = types.TxJSON{
+			Type:                 hexutil.Uint64(dec.Type),
+			ChainID:              (*hexutil.Big)(dec.ChainID),
+			Nonce:                (*hexutil.Uint64)(dec.Nonce),
+			Gas:                  (*hexutil.Uint64)(dec.Gas),
+			GasPrice:             (*hexutil.Big)(dec.GasPrice),
+			MaxPriorityFeePerGas: (*hexutil.Big)(dec.MaxPriorityFeePerGas),
+			MaxFeePerGas:         (*hexutil.Big)(dec.MaxFeePerGas),
+			MaxFeePerDataGas:     (*hexutil.Big)(dec.MaxFeePerDataGas),
+			Value:                (*hexutil.Big)(dec.Value),
+			Input:                (*hexutil.Bytes)(dec.Input),
+			Blobs:                bytesListFromCBOR(dec.Blobs),
+			Commitments:          bytesListFromCBOR(dec.Commitments),
+			Proofs:               bytesListFromCBOR(dec.Proofs),
+			V:                    (*hexutil.Big)(dec.V),
+			R:                    (*hexutil.Big)(dec.R),
+			S:                    (*hexutil.Big)(dec.S),
+			Mint:                 (*hexutil.Big)(dec.Mint),
+			IsSystemTx:           dec.IsSystemTx,
+		}
+		err error
+	)
+	if enc.To, err = addressFromBytes("to", dec.To); err != nil {
+		return nil, err
+	}
+	if enc.From, err = addressFromBytes("from", dec.From); err != nil {
+		return nil, err
+	}
+	if enc.SourceHash, err = hashFromBytes("sourceHash", dec.SourceHash); err != nil {
+		return nil, err
+	}
+	if dec.BlobVersionedHashes != nil {
+		enc.BlobVersionedHashes = make([]common.Hash, len(dec.BlobVersionedHashes))
+		for i, b := range dec.BlobVersionedHashes {
+			h, err := hashFromBytes("blobVersionedHashes", b)
+			if err != nil {
+				return nil, err
+			}
+			enc.BlobVersionedHashes[i] = *h
+		}
+	}
+	if dec.AccessList != nil {
+		al := make(types.AccessList, len(*dec.AccessList))
+		for i, tuple := range *dec.AccessList {
+			addr, err := addressFromBytes("accessList", tuple.Address)
+			if err != nil {
+				return nil, err
+			}
+			al[i].Address = *addr
+			al[i].StorageKeys = make([]common.Hash, len(tuple.StorageKeys))
+			for j, key := range tuple.StorageKeys {
+				h, err := hashFromBytes("accessList", key)
+				if err != nil {
+					return nil, err
+				}
+				al[i].StorageKeys[j] = *h
+			}
+		}
+		enc.AccessList = &al
+	}
+	return types.DecodeTxJSON(&enc)
+}
+
+func addressToCBOR(a *common.Address) []byte {
+	if a == nil {
+		return nil
+	}
+	return a.Bytes()
+}
+
+func hashToCBOR(h *common.Hash) []byte {
+	if h == nil {
+		return nil
+	}
+	return h.Bytes()
+}
+
+func hashesToCBOR(hashes []common.Hash) [][]byte {
+	if hashes == nil {
+		return nil
+	}
+	enc := make([][]byte, len(hashes))
+	for i := range hashes {
+		enc[i] = hashes[i].Bytes()
+	}
+	return enc
+}
+
+func bytesListToCBOR(list []hexutil.Bytes) [][]byte {
+	if list == nil {
+		return nil
+	}
+	enc := make([][]byte, len(list))
+	for i := range list {
+		enc[i] = list[i]
+	}
+	return enc
+}
+
+func bytesListFromCBOR(list [][]byte) []hexutil.Bytes {
+	if list == nil {
+		return nil
+	}
+	dec := make([]hexutil.Bytes, len(list))
+	for i := range list {
+		dec[i] = list[i]
+	}
+	return dec
+}
+
+func accessListToCBOR(al *types.AccessList) *[]accessTupleCBOR {
+	if al == nil {
+		return nil
+	}
+	enc := make([]accessTupleCBOR, len(*al))
+	for i, tuple := range *al {
+		enc[i] = accessTupleCBOR{
+			Address:     tuple.Address.Bytes(),
+			StorageKeys: hashesToCBOR(tuple.StorageKeys),
+		}
+	}
+	return &enc
+}
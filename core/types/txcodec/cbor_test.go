@@ -0,0 +1,87 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package txcodec
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto/kzg4844"
+	"github.com/holiman/uint256"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCBORRoundTrip(t *testing.T) {
+	for i, tx := range testTxs(t) {
+		enc, err := MarshalCBOR(tx)
+		if err != nil {
+			t.Fatalf("test %d: failed to marshal tx: %v", i, err)
+		}
+		dec, err := UnmarshalCBOR(enc)
+		if err != nil {
+			t.Fatalf("test %d: failed to unmarshal tx: %v", i, err)
+		}
+		if err := assertEqual(tx, dec); err != nil {
+			t.Errorf("test %d: %v", i, err)
+		}
+	}
+}
+
+func TestCBORDeterministic(t *testing.T) {
+	for i, tx := range testTxs(t) {
+		first, err := MarshalCBOR(tx)
+		if err != nil {
+			t.Fatalf("test %d: failed to marshal tx: %v", i, err)
+		}
+		for j := 0; j < 10; j++ {
+			enc, err := MarshalCBOR(tx)
+			if err != nil {
+				t.Fatalf("test %d: failed to marshal tx: %v", i, err)
+			}
+			if !bytes.Equal(enc, first) {
+				t.Fatalf("test %d: non-deterministic encoding, have %x want %x", i, enc, first)
+			}
+		}
+	}
+}
+
+func TestCBORBlobSidecar(t *testing.T) {
+	sidecar := &types.BlobTxSidecar{
+		Blobs:       []kzg4844.Blob{{0x01}, {0x02}},
+		Commitments: []kzg4844.Commitment{{0x03}, {0x04}},
+		Proofs:      []kzg4844.Proof{{0x05}, {0x06}},
+	}
+	for _, sc := range []*types.BlobTxSidecar{nil, sidecar} {
+		tx := types.NewTx(&types.BlobTx{
+			ChainID:    uint256.NewInt(1),
+			Nonce:      1,
+			Gas:        21000,
+			To:         &common.Address{0x01},
+			BlobHashes: sidecar.BlobHashes(),
+			Sidecar:    sc,
+		})
+		enc, err := MarshalCBOR(tx)
+		require.NoError(t, err)
+
+		dec, err := UnmarshalCBOR(enc)
+		require.NoError(t, err)
+		require.Equal(t, tx.Hash(), dec.Hash())
+		require.Equal(t, sc, dec.BlobTxSidecar())
+	}
+}
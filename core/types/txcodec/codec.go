@@ -0,0 +1,48 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package txcodec implements binary encodings of transactions other than RLP.
+// They are built on types.TxJSON, so they carry the same fields as the JSON
+// encoding and apply the same checks when decoding.
+package txcodec
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func addressFromBytes(field string, b []byte) (*common.Address, error) {
+	if b == nil {
+		return nil, nil
+	}
+	if len(b) != common.AddressLength {
+		return nil, fmt.Errorf("invalid length %d for field '%s' in transaction", len(b), field)
+	}
+	addr := common.BytesToAddress(b)
+	return &addr, nil
+}
+
+func hashFromBytes(field string, b []byte) (*common.Hash, error) {
+	if b == nil {
+		return nil, nil
+	}
+	if len(b) != common.HashLength {
+		return nil, fmt.Errorf("invalid length %d for field '%s' in transaction", len(b), field)
+	}
+	h := common.BytesToHash(b)
+	return &h, nil
+}
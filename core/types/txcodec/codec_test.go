@@ -0,0 +1,76 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package txcodec
+
+import (
+	"fmt"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/holiman/uint256"
+)
+
+// testTxs returns a transaction of every type.
+func testTxs(t testing.TB) []*types.Transaction {
+	key, _ := crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	signer := types.NewCancunSigner(big.NewInt(123))
+	to := common.HexToAddress("0x01")
+	var txs []*types.Transaction
+	for i, txdata := range []types.TxData{
+		&types.LegacyTx{Nonce: 1, GasPrice: big.NewInt(500), Gas: 1000000, To: &to, Value: big.NewInt(1), Data: []byte("abcdef")},
+		&types.AccessListTx{ChainID: big.NewInt(123), Nonce: 1, GasPrice: big.NewInt(500), Gas: 1000000, To: &to, Value: big.NewInt(1),
+			AccessList: types.AccessList{{Address: to, StorageKeys: []common.Hash{{0x01}}}}},
+		&types.DynamicFeeTx{ChainID: big.NewInt(123), Nonce: 1, Gas: 1000000, To: &to, Value: big.NewInt(1), GasTipCap: big.NewInt(500), GasFeeCap: big.NewInt(500)},
+		&types.BlobTx{ChainID: uint256.NewInt(123), Nonce: 1, Gas: 1000000, To: &to, Value: uint256.NewInt(1), GasTipCap: uint256.NewInt(500),
+			GasFeeCap: uint256.NewInt(500), BlobFeeCap: uint256.NewInt(1), BlobHashes: []common.Hash{{0x01}}},
+	} {
+		tx, err := types.SignNewTx(key, signer, txdata)
+		if err != nil {
+			t.Fatalf("test %d: %v", i, err)
+		}
+		txs = append(txs, tx)
+	}
+	txs = append(txs, types.NewTx(&types.DepositTx{
+		SourceHash: common.HexToHash("0x1234"),
+		From:       to,
+		To:         &to,
+		Mint:       big.NewInt(34),
+		Value:      big.NewInt(1),
+		Gas:        1000000,
+		Data:       []byte("abcdef"),
+	}))
+	return txs
+}
+
+func assertEqual(orig *types.Transaction, cpy *types.Transaction) error {
+	if want, got := orig.Hash(), cpy.Hash(); want != got {
+		return fmt.Errorf("parsed tx differs from original tx, want %v, got %v", want, got)
+	}
+	if want, got := orig.ChainId(), cpy.ChainId(); want.Cmp(got) != 0 {
+		return fmt.Errorf("invalid chain id, want %d, got %d", want, got)
+	}
+	if orig.AccessList() != nil {
+		if !reflect.DeepEqual(orig.AccessList(), cpy.AccessList()) {
+			return fmt.Errorf("access list mismatch")
+		}
+	}
+	return nil
+}
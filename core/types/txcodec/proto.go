@@ -0,0 +1,324 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package txcodec
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// The protobuf representation of transactions has the same field layout as
+// types.TxJSON. 64-bit quantities are stored as varints, arbitrary-precision
+// ones as big-endian byte strings without leading zeros. The schema is defined
+// in transaction.proto, whose field numbers must match the constants below.
+const (
+	protoTxType protowire.Number = iota + 1
+	protoTxChainID
+	protoTxNonce
+	protoTxTo
+	protoTxGas
+	protoTxGasPrice
+	protoTxMaxPriorityFeePerGas
+	protoTxMaxFeePerGas
+	protoTxMaxFeePerDataGas
+	protoTxValue
+	protoTxInput
+	protoTxAccessList
+	protoTxBlobVersionedHashes
+	protoTxBlobs
+	protoTxCommitments
+	protoTxProofs
+	protoTxV
+	protoTxR
+	protoTxS
+	protoTxSourceHash
+	protoTxFrom
+	protoTxMint
+	protoTxIsSystemTx
+	protoTxHash
+)
+
+const (
+	protoTupleAddress protowire.Number = iota + 1
+	protoTupleStorageKeys
+)
+
+// MarshalProto marshals a transaction as protobuf with a hash. Fields are
+// written in field number order, so that repeated encodings are byte-identical.
+func MarshalProto(tx *types.Transaction) ([]byte, error) {
+	enc := tx.EncodeTxJSON()
+
+	var b []byte
+	if enc.Type != 0 {
+		b = protowire.AppendTag(b, protoTxType, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(enc.Type))
+	}
+	b = appendProtoBig(b, protoTxChainID, enc.ChainID)
+	b = appendProtoUint64(b, protoTxNonce, enc.Nonce)
+	if enc.To != nil {
+		b = appendProtoBytes(b, protoTxTo, enc.To.Bytes())
+	}
+	b = appendProtoUint64(b, protoTxGas, enc.Gas)
+	b = appendProtoBig(b, protoTxGasPrice, enc.GasPrice)
+	b = appendProtoBig(b, protoTxMaxPriorityFeePerGas, enc.MaxPriorityFeePerGas)
+	b = appendProtoBig(b, protoTxMaxFeePerGas, enc.MaxFeePerGas)
+	b = appendProtoBig(b, protoTxMaxFeePerDataGas, enc.MaxFeePerDataGas)
+	b = appendProtoBig(b, protoTxValue, enc.Value)
+	if enc.Input != nil {
+		b = appendProtoBytes(b, protoTxInput, *enc.Input)
+	}
+	if enc.AccessList != nil {
+		for _, tuple := range *enc.AccessList {
+			var t []byte
+			t = appendProtoBytes(t, protoTupleAddress, tuple.Address.Bytes())
+			for _, key := range tuple.StorageKeys {
+				t = appendProtoBytes(t, protoTupleStorageKeys, key.Bytes())
+			}
+			b = appendProtoBytes(b, protoTxAccessList, t)
+		}
+	}
+	for _, h := range enc.BlobVersionedHashes {
+		b = appendProtoBytes(b, protoTxBlobVersionedHashes, h.Bytes())
+	}
+	for _, blob := range enc.Blobs {
+		b = appendProtoBytes(b, protoTxBlobs, blob)
+	}
+	for _, commitment := range enc.Commitments {
+		b = appendProtoBytes(b, protoTxCommitments, commitment)
+	}
+	for _, proof := range enc.Proofs {
+		b = appendProtoBytes(b, protoTxProofs, proof)
+	}
+	b = appendProtoBig(b, protoTxV, enc.V)
+	b = appendProtoBig(b, protoTxR, enc.R)
+	b = appendProtoBig(b, protoTxS, enc.S)
+	if enc.SourceHash != nil {
+		b = appendProtoBytes(b, protoTxSourceHash, enc.SourceHash.Bytes())
+	}
+	if enc.From != nil {
+		b = appendProtoBytes(b, protoTxFrom, enc.From.Bytes())
+	}
+	b = appendProtoBig(b, protoTxMint, enc.Mint)
+	if enc.IsSystemTx != nil {
+		b = protowire.AppendTag(b, protoTxIsSystemTx, protowire.VarintType)
+		b = protowire.AppendVarint(b, protowire.EncodeBool(*enc.IsSystemTx))
+	}
+	b = appendProtoBytes(b, protoTxHash, enc.Hash.Bytes())
+	return b, nil
+}
+
+// UnmarshalProto unmarshals a transaction from protobuf, applying the same checks
+// as UnmarshalJSON. Unknown fields are ignored.
+func UnmarshalProto(input []byte) (*types.Transaction, error) {
+	var dec types.TxJSON
+	for len(input) > 0 {
+		num, typ, n := protowire.ConsumeTag(input)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		input = input[n:]
+
+		var (
+			val  []byte
+			uval uint64
+		)
+		switch typ {
+		case protowire.VarintType:
+			uval, n = protowire.ConsumeVarint(input)
+		case protowire.BytesType:
+			val, n = protowire.ConsumeBytes(input)
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, input)
+		}
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		input = input[n:]
+		if err := checkProtoWireType(num, typ); err != nil {
+			return nil, err
+		}
+
+		var err error
+		switch num {
+		case protoTxType:
+			dec.Type = hexutil.Uint64(uval)
+		case protoTxChainID:
+			dec.ChainID = protoBig(val)
+		case protoTxNonce:
+			dec.Nonce = (*hexutil.Uint64)(&uval)
+		case protoTxTo:
+			dec.To, err = addressFromBytes("to", val)
+		case protoTxGas:
+			dec.Gas = (*hexutil.Uint64)(&uval)
+		case protoTxGasPrice:
+			dec.GasPrice = protoBig(val)
+		case protoTxMaxPriorityFeePerGas:
+			dec.MaxPriorityFeePerGas = protoBig(val)
+		case protoTxMaxFeePerGas:
+			dec.MaxFeePerGas = protoBig(val)
+		case protoTxMaxFeePerDataGas:
+			dec.MaxFeePerDataGas = protoBig(val)
+		case protoTxValue:
+			dec.Value = protoBig(val)
+		case protoTxInput:
+			data := hexutil.Bytes(common.CopyBytes(val))
+			if data == nil {
+				data = hexutil.Bytes{}
+			}
+			dec.Input = &data
+		case protoTxAccessList:
+			var tuple types.AccessTuple
+			if tuple, err = decodeProtoAccessTuple(val); err == nil {
+				if dec.AccessList == nil {
+					dec.AccessList = new(types.AccessList)
+				}
+				*dec.AccessList = append(*dec.AccessList, tuple)
+			}
+		case protoTxBlobVersionedHashes:
+			var h *common.Hash
+			if h, err = hashFromBytes("blobVersionedHashes", val); err == nil {
+				dec.BlobVersionedHashes = append(dec.BlobVersionedHashes, *h)
+			}
+		case protoTxBlobs:
+			dec.Blobs = append(dec.Blobs, common.CopyBytes(val))
+		case protoTxCommitments:
+			dec.Commitments = append(dec.Commitments, common.CopyBytes(val))
+		case protoTxProofs:
+			dec.Proofs = append(dec.Proofs, common.CopyBytes(val))
+		case protoTxV:
+			dec.V = protoBig(val)
+		case protoTxR:
+			dec.R = protoBig(val)
+		case protoTxS:
+			dec.S = protoBig(val)
+		case protoTxSourceHash:
+			dec.SourceHash, err = hashFromBytes("sourceHash", val)
+		case protoTxFrom:
+			dec.From, err = addressFromBytes("from", val)
+		case protoTxMint:
+			dec.Mint = protoBig(val)
+		case protoTxIsSystemTx:
+			isSystemTx := protowire.DecodeBool(uval)
+			dec.IsSystemTx = &isSystemTx
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	// An empty repeated field is indistinguishable from a missing one, but the
+	// access list is part of all transaction types which can carry one.
+	switch dec.Type {
+	case types.AccessListTxType, types.DynamicFeeTxType, types.BlobTxType:
+		if dec.AccessList == nil {
+			dec.AccessList = &types.AccessList{}
+		}
+	}
+	return types.DecodeTxJSON(&dec)
+}
+
+// checkProtoWireType verifies that a known transaction field has the wire type
+// of its schema definition.
+func checkProtoWireType(num protowire.Number, typ protowire.Type) error {
+	var want protowire.Type
+	switch num {
+	case protoTxType, protoTxNonce, protoTxGas, protoTxIsSystemTx:
+		want = protowire.VarintType
+	case protoTxChainID, protoTxTo, protoTxGasPrice, protoTxMaxPriorityFeePerGas, protoTxMaxFeePerGas,
+		protoTxMaxFeePerDataGas, protoTxValue, protoTxInput, protoTxAccessList, protoTxBlobVersionedHashes,
+		protoTxBlobs, protoTxCommitments, protoTxProofs, protoTxV, protoTxR, protoTxS, protoTxSourceHash,
+		protoTxFrom, protoTxMint, protoTxHash:
+		want = protowire.BytesType
+	default:
+		return nil
+	}
+	if typ != want {
+		return fmt.Errorf("invalid wire type %d for protobuf field %d in transaction", typ, num)
+	}
+	return nil
+}
+
+func decodeProtoAccessTuple(b []byte) (types.AccessTuple, error) {
+	tuple := types.AccessTuple{StorageKeys: []common.Hash{}}
+	var hasAddress bool
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return tuple, protowire.ParseError(n)
+		}
+		b = b[n:]
+		if typ != protowire.BytesType {
+			if n = protowire.ConsumeFieldValue(num, typ, b); n < 0 {
+				return tuple, protowire.ParseError(n)
+			}
+			b = b[n:]
+			continue
+		}
+		val, n := protowire.ConsumeBytes(b)
+		if n < 0 {
+			return tuple, protowire.ParseError(n)
+		}
+		b = b[n:]
+		switch num {
+		case protoTupleAddress:
+			addr, err := addressFromBytes("accessList", val)
+			if err != nil {
+				return tuple, err
+			}
+			tuple.Address, hasAddress = *addr, true
+		case protoTupleStorageKeys:
+			key, err := hashFromBytes("accessList", val)
+			if err != nil {
+				return tuple, err
+			}
+			tuple.StorageKeys = append(tuple.StorageKeys, *key)
+		}
+	}
+	if !hasAddress {
+		return tuple, errors.New("missing required field 'address' in transaction access list")
+	}
+	return tuple, nil
+}
+
+func appendProtoBytes(b []byte, num protowire.Number, v []byte) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, v)
+}
+
+func appendProtoUint64(b []byte, num protowire.Number, v *hexutil.Uint64) []byte {
+	if v == nil {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, uint64(*v))
+}
+
+func appendProtoBig(b []byte, num protowire.Number, v *hexutil.Big) []byte {
+	if v == nil {
+		return b
+	}
+	return appendProtoBytes(b, num, (*big.Int)(v).Bytes())
+}
+
+func protoBig(b []byte) *hexutil.Big {
+	return (*hexutil.Big)(new(big.Int).SetBytes(b))
+}
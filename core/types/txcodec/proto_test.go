@@ -0,0 +1,150 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package txcodec
+
+import (
+	"bytes"
+	"math/big"
+	"os"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func TestProtoRoundTrip(t *testing.T) {
+	txs := append(testTxs(t), types.NewTx(&types.DepositTx{
+		SourceHash:          common.HexToHash("0x5678"),
+		From:                common.HexToAddress("0x02"),
+		Value:               big.NewInt(0),
+		Gas:                 1000000,
+		IsSystemTransaction: true,
+	}))
+	for i, tx := range txs {
+		enc, err := MarshalProto(tx)
+		if err != nil {
+			t.Fatalf("test %d: failed to marshal tx: %v", i, err)
+		}
+		dec, err := UnmarshalProto(enc)
+		if err != nil {
+			t.Fatalf("test %d: failed to unmarshal tx: %v", i, err)
+		}
+		if err := assertEqual(tx, dec); err != nil {
+			t.Errorf("test %d: %v", i, err)
+		}
+		if tx.IsSystemTx() != dec.IsSystemTx() || tx.Mint().Cmp(dec.Mint()) != 0 {
+			t.Errorf("test %d: deposit fields mismatch", i)
+		}
+	}
+}
+
+func TestProtoDeterministic(t *testing.T) {
+	for i, tx := range testTxs(t) {
+		first, err := MarshalProto(tx)
+		if err != nil {
+			t.Fatalf("test %d: failed to marshal tx: %v", i, err)
+		}
+		for j := 0; j < 10; j++ {
+			enc, err := MarshalProto(tx)
+			if err != nil {
+				t.Fatalf("test %d: failed to marshal tx: %v", i, err)
+			}
+			if !bytes.Equal(enc, first) {
+				t.Fatalf("test %d: non-deterministic encoding, have %x want %x", i, enc, first)
+			}
+		}
+		// Re-encoding a decoded transaction yields the same bytes.
+		dec, err := UnmarshalProto(first)
+		if err != nil {
+			t.Fatalf("test %d: failed to unmarshal tx: %v", i, err)
+		}
+		enc, err := MarshalProto(dec)
+		if err != nil {
+			t.Fatalf("test %d: failed to marshal tx: %v", i, err)
+		}
+		if !bytes.Equal(enc, first) {
+			t.Errorf("test %d: re-encoding mismatch, have %x want %x", i, enc, first)
+		}
+	}
+}
+
+func TestProtoInvalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		input []byte
+		err   string
+	}{
+		{"Truncated", []byte{0x22, 0x14, 0x01}, "unexpected EOF"},
+		{"Wrong wire type", []byte{0x28, 0x01, 0x2a, 0x00}, "invalid wire type 2 for protobuf field 5 in transaction"},
+		{"Short address", []byte{0x22, 0x01, 0x01}, "invalid length 1 for field 'to' in transaction"},
+		{"Missing fields", []byte{0x08, 0x02}, "missing required field 'chainId' in transaction"},
+	}
+	for _, test := range tests {
+		_, err := UnmarshalProto(test.input)
+		if err == nil || err.Error() != test.err {
+			t.Errorf("%s: error mismatch: have %v, want %q", test.name, err, test.err)
+		}
+	}
+}
+
+// TestProtoSchema checks that the field numbers of transaction.proto match the
+// ones used by the codec.
+func TestProtoSchema(t *testing.T) {
+	schema, err := os.ReadFile("transaction.proto")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]map[string]protowire.Number{
+		"Transaction": {
+			"type": protoTxType, "chain_id": protoTxChainID, "nonce": protoTxNonce, "to": protoTxTo,
+			"gas": protoTxGas, "gas_price": protoTxGasPrice, "max_priority_fee_per_gas": protoTxMaxPriorityFeePerGas,
+			"max_fee_per_gas": protoTxMaxFeePerGas, "max_fee_per_data_gas": protoTxMaxFeePerDataGas,
+			"value": protoTxValue, "input": protoTxInput, "access_list": protoTxAccessList,
+			"blob_versioned_hashes": protoTxBlobVersionedHashes, "blobs": protoTxBlobs,
+			"commitments": protoTxCommitments, "proofs": protoTxProofs, "v": protoTxV, "r": protoTxR, "s": protoTxS,
+			"source_hash": protoTxSourceHash, "from": protoTxFrom, "mint": protoTxMint,
+			"is_system_tx": protoTxIsSystemTx, "hash": protoTxHash,
+		},
+		"AccessTuple": {
+			"address": protoTupleAddress, "storage_keys": protoTupleStorageKeys,
+		},
+	}
+	var (
+		messageRE = regexp.MustCompile(`^message (\w+) {$`)
+		fieldRE   = regexp.MustCompile(`^(?:optional |repeated )?\w+ (\w+) = (\d+);$`)
+		have      = make(map[string]map[string]protowire.Number)
+		message   string
+	)
+	for _, line := range strings.Split(string(schema), "\n") {
+		line = strings.TrimSpace(line)
+		if m := messageRE.FindStringSubmatch(line); m != nil {
+			message = m[1]
+			have[message] = make(map[string]protowire.Number)
+		} else if m := fieldRE.FindStringSubmatch(line); m != nil && message != "" {
+			num, _ := strconv.Atoi(m[2])
+			have[message][m[1]] = protowire.Number(num)
+		}
+	}
+	if !reflect.DeepEqual(have, want) {
+		t.Errorf("schema mismatch:\nhave %v\nwant %v", have, want)
+	}
+}
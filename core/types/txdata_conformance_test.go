@@ -0,0 +1,128 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/holiman/uint256"
+)
+
+// assertTxDataConformance checks that a TxData implementation follows the
+// contract the Transaction accessors rely on:
+//
+//   - copy returns an independent deep copy of the same type and encoding,
+//   - chainID, gasPrice, gasTipCap, gasFeeCap, value and the signature values
+//     of the copy are never nil,
+//   - the blob accessors are consistent with each other,
+//   - effectiveGasPrice returns a non-nil value which is not shared with the
+//     transaction, with or without a base fee,
+//   - only deposits are system transactions.
+//
+// It should be run for every transaction type, including zero-valued ones. The
+// TxData methods are unexported, so only types of this package can be checked.
+func assertTxDataConformance(t testing.TB, d TxData) {
+	t.Helper()
+
+	cpy := d.copy()
+	if cpy == d {
+		t.Fatalf("type %#x: copy returned the same instance", d.txType())
+	}
+	if cpy.txType() != d.txType() {
+		t.Errorf("type %#x: copy has type %#x", d.txType(), cpy.txType())
+	}
+	want, err := rlp.EncodeToBytes(d)
+	if err != nil {
+		t.Fatalf("type %#x: failed to encode: %v", d.txType(), err)
+	}
+	have, err := rlp.EncodeToBytes(cpy)
+	if err != nil {
+		t.Fatalf("type %#x: failed to encode copy: %v", d.txType(), err)
+	}
+	if !bytes.Equal(have, want) {
+		t.Errorf("type %#x: copy encoding mismatch: have %x, want %x", d.txType(), have, want)
+	}
+
+	for name, v := range map[string]*big.Int{
+		"chainID":   cpy.chainID(),
+		"gasPrice":  cpy.gasPrice(),
+		"gasTipCap": cpy.gasTipCap(),
+		"gasFeeCap": cpy.gasFeeCap(),
+		"value":     cpy.value(),
+	} {
+		if v == nil {
+			t.Errorf("type %#x: %s is nil", d.txType(), name)
+		}
+	}
+	if v, r, s := cpy.rawSignatureValues(); v == nil || r == nil || s == nil {
+		t.Errorf("type %#x: nil signature values: v %v, r %v, s %v", d.txType(), v, r, s)
+	}
+
+	if have, want := cpy.blobGas(), params.BlobTxDataGasPerBlob*uint64(len(cpy.blobHashes())); have != want {
+		t.Errorf("type %#x: blob gas %d for %d blob hashes, want %d", d.txType(), have, len(cpy.blobHashes()), want)
+	}
+	if len(cpy.blobHashes()) > 0 && cpy.blobGasFeeCap() == nil {
+		t.Errorf("type %#x: nil blob fee cap with blob hashes", d.txType())
+	}
+
+	for _, baseFee := range []*big.Int{nil, big.NewInt(7)} {
+		price := cpy.effectiveGasPrice(new(big.Int), baseFee)
+		if price == nil {
+			t.Errorf("type %#x: nil effective gas price for base fee %v", d.txType(), baseFee)
+			continue
+		}
+		for _, field := range []*big.Int{cpy.gasPrice(), cpy.gasTipCap(), cpy.gasFeeCap(), cpy.value()} {
+			if price == field {
+				t.Errorf("type %#x: effective gas price shares storage with the transaction", d.txType())
+			}
+		}
+	}
+
+	// Mutating the copy must not affect the original.
+	if data := cpy.data(); len(data) > 0 {
+		data[0]++
+		if bytes.Equal(cpy.data(), d.data()) {
+			t.Errorf("type %#x: copy shares calldata with the original", d.txType())
+		}
+	}
+
+	if cpy.isSystemTx() && cpy.txType() != DepositTxType {
+		t.Errorf("type %#x: non-deposit system transaction", d.txType())
+	}
+}
+
+func TestTxDataConformance(t *testing.T) {
+	to := common.HexToAddress("0x01")
+	for _, d := range []TxData{
+		new(LegacyTx),
+		new(AccessListTx),
+		new(DynamicFeeTx),
+		new(BlobTx),
+		&LegacyTx{Nonce: 1, GasPrice: big.NewInt(2), Gas: 3, To: &to, Value: big.NewInt(4), Data: []byte{5}, V: big.NewInt(27), R: big.NewInt(1), S: big.NewInt(1)},
+		&AccessListTx{ChainID: big.NewInt(1), GasPrice: big.NewInt(2), To: &to, Value: big.NewInt(4), Data: []byte{5}, AccessList: AccessList{{Address: to}}},
+		&DynamicFeeTx{ChainID: big.NewInt(1), GasTipCap: big.NewInt(2), GasFeeCap: big.NewInt(3), To: &to, Value: big.NewInt(4), Data: []byte{5}},
+		&BlobTx{ChainID: uint256.NewInt(1), GasTipCap: uint256.NewInt(2), GasFeeCap: uint256.NewInt(3), To: &to, Value: uint256.NewInt(4), Data: []byte{5},
+			BlobFeeCap: uint256.NewInt(6), BlobHashes: []common.Hash{{0x01}}},
+	} {
+		assertTxDataConformance(t, d)
+	}
+}